@@ -0,0 +1,20 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApprovalGate holds the order before the step until it is approved through spec.approvals.
+type ApprovalGate struct {
+	// Message tells the approvers what they sign off, it is shown in the AwaitingApproval condition.
+	Message string `json:"message,omitempty"`
+}
+
+// Approval signs off the approval step named Step.
+type Approval struct {
+	Step string `json:"step"`
+
+	// ApprovedBy and ApprovedAt are set by the admission webhook from the request adding the approval.
+	ApprovedBy string       `json:"approvedBy,omitempty"`
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+}
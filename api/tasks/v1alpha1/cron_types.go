@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CronOrderStep creates an OrderStep from its template on a cron schedule.
+type CronOrderStep struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CronOrderStepSpec   `json:"spec,omitempty"`
+	Status CronOrderStepStatus `json:"status,omitempty"`
+}
+
+type CronOrderStepSpec struct {
+	// Schedule is a standard cron expression, e.g. "0 2 * * *".
+	Schedule string `json:"schedule"`
+
+	// TimeZone is the IANA name the schedule is read in, the time zone of the operator by default.
+	TimeZone *string `json:"timeZone,omitempty"`
+
+	// ConcurrencyPolicy tells what to do when the previous OrderStep is still running, Allow by default.
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+	// StartingDeadlineSeconds is how late an OrderStep may still be created after its scheduled time,
+	// the runs missed by more are not created.
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// Suspend stops creating OrderSteps, the running ones are left alone.
+	Suspend *bool `json:"suspend,omitempty"`
+
+	// SuccessfulHistoryLimit and FailedHistoryLimit are how many finished OrderSteps are kept,
+	// 3 and 1 by default. Cancelled OrderSteps count as failed.
+	SuccessfulHistoryLimit *int32 `json:"successfulHistoryLimit,omitempty"`
+	FailedHistoryLimit     *int32 `json:"failedHistoryLimit,omitempty"`
+
+	Template OrderStepTemplateSpec `json:"template"`
+}
+
+// OrderStepTemplateSpec is what every OrderStep created by a CronOrderStep is made of.
+type OrderStepTemplateSpec struct {
+	// Labels and Annotations are copied to the OrderStep.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	Spec OrderStepSpec `json:"spec"`
+}
+
+type ConcurrencyPolicy string
+
+const (
+	ConcurrencyPolicyAllow   ConcurrencyPolicy = "Allow"
+	ConcurrencyPolicyForbid  ConcurrencyPolicy = "Forbid"
+	ConcurrencyPolicyReplace ConcurrencyPolicy = "Replace"
+)
+
+const (
+	// CronOrderStepLabel is set on the OrderSteps created by a CronOrderStep to its name.
+	CronOrderStepLabel = OrderTaskGroup + "/cron-order-step"
+	// ScheduledAtAnnotation holds the RFC3339 time an OrderStep created by a CronOrderStep was scheduled at.
+	ScheduledAtAnnotation = OrderTaskGroup + "/scheduled-at"
+)
+
+type CronOrderStepStatus struct {
+	// Active are the OrderSteps created by the CronOrderStep which have not finished.
+	Active []corev1.ObjectReference `json:"active,omitempty"`
+
+	LastScheduleTime   *metav1.Time `json:"lastScheduleTime,omitempty"`
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CronOrderStepList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []CronOrderStep `json:"items"`
+}
@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+// +groupName=tasks.chengdai.com
+
+// Package v1alpha1 is the v1alpha1 version of the tasks API.
+package v1alpha1
@@ -0,0 +1,64 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type ParamType string
+
+const (
+	ParamTypeString ParamType = "string"
+	ParamTypeArray  ParamType = "array"
+)
+
+type ParamSpec struct {
+	Name string `json:"name"`
+	// Type defaults to string, an array param is expanded with $(params.<name>[*])
+	// as a whole element of command or args.
+	Type        ParamType   `json:"type,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     *ParamValue `json:"default,omitempty"`
+}
+
+// ParamValue is either a string or an array of strings.
+type ParamValue struct {
+	Type      ParamType
+	StringVal string
+	ArrayVal  []string
+}
+
+func NewStringParamValue(value string) *ParamValue {
+	return &ParamValue{Type: ParamTypeString, StringVal: value}
+}
+
+func NewArrayParamValue(values ...string) *ParamValue {
+	return &ParamValue{Type: ParamTypeArray, ArrayVal: values}
+}
+
+func (pv *ParamValue) UnmarshalJSON(data []byte) error {
+	if len(data) != 0 && data[0] == '[' {
+		pv.Type = ParamTypeArray
+		return json.Unmarshal(data, &pv.ArrayVal)
+	}
+	pv.Type = ParamTypeString
+	if err := json.Unmarshal(data, &pv.StringVal); err != nil {
+		return fmt.Errorf("param value must be a string or an array of strings: %w", err)
+	}
+	return nil
+}
+
+func (pv ParamValue) MarshalJSON() ([]byte, error) {
+	if pv.Type == ParamTypeArray {
+		return json.Marshal(pv.ArrayVal)
+	}
+	return json.Marshal(pv.StringVal)
+}
+
+// OpenAPISchemaType is used by the kube-openapi generator when constructing
+// the OpenAPI spec of this type, nil means the value is kept as it is.
+func (ParamValue) OpenAPISchemaType() []string { return nil }
+
+// OpenAPISchemaFormat is used by the kube-openapi generator when constructing
+// the OpenAPI spec of this type.
+func (ParamValue) OpenAPISchemaFormat() string { return "" }
@@ -0,0 +1,44 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParamValueJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *ParamValue
+		wantErr bool
+	}{
+		{name: "string", data: `"prod"`, want: NewStringParamValue("prod")},
+		{name: "empty string", data: `""`, want: NewStringParamValue("")},
+		{name: "array", data: `["-v","-race"]`, want: NewArrayParamValue("-v", "-race")},
+		{name: "number", data: `1`, wantErr: true},
+		{name: "array of numbers", data: `[1]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &ParamValue{}
+			err := json.Unmarshal([]byte(tt.data), got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected an error: %t, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.data {
+				t.Errorf("expected %s to be written back, got %s", tt.data, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodTemplate holds the fields merged into the pod running the steps.
+type PodTemplate struct {
+	NodeSelector       map[string]string             `json:"nodeSelector,omitempty"`
+	Tolerations        []corev1.Toleration           `json:"tolerations,omitempty"`
+	Affinity           *corev1.Affinity              `json:"affinity,omitempty"`
+	ServiceAccountName string                        `json:"serviceAccountName,omitempty"`
+	PriorityClassName  string                        `json:"priorityClassName,omitempty"`
+	SecurityContext    *corev1.PodSecurityContext    `json:"securityContext,omitempty"`
+	ImagePullSecrets   []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	RuntimeClassName   *string                       `json:"runtimeClassName,omitempty"`
+}
@@ -0,0 +1,59 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	OrderTaskGroup           = "tasks.chengdai.com"
+	OrderTaskVersion         = "v1alpha1"
+	OrderTaskApiVersionGroup = OrderTaskGroup + "/" + OrderTaskVersion
+	OrderTaskResourceKind    = "OrderStep"
+	OrderTaskResourcePlural  = "ordersteps"
+	OrderTaskCRDName         = OrderTaskResourcePlural + "." + OrderTaskGroup
+
+	CronOrderStepResourceKind   = "CronOrderStep"
+	CronOrderStepResourcePlural = "cronordersteps"
+	CronOrderStepCRDName        = CronOrderStepResourcePlural + "." + OrderTaskGroup
+
+	OrderStepRunResourceKind   = "OrderStepRun"
+	OrderStepRunResourcePlural = "orderstepruns"
+	OrderStepRunCRDName        = OrderStepRunResourcePlural + "." + OrderTaskGroup
+
+	OrderStepTemplateResourcePlural        = "ordersteptemplates"
+	OrderStepTemplateCRDName               = OrderStepTemplateResourcePlural + "." + OrderTaskGroup
+	ClusterOrderStepTemplateResourcePlural = "clusterordersteptemplates"
+	ClusterOrderStepTemplateCRDName        = ClusterOrderStepTemplateResourcePlural + "." + OrderTaskGroup
+)
+
+// SchemeGroupVersion is group version used to register these objects
+var SchemeGroupVersion = schema.GroupVersion{Group: OrderTaskGroup, Version: OrderTaskVersion}
+
+var (
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	AddToScheme   = SchemeBuilder.AddToScheme
+)
+
+// Resource takes an unqualified resource and returns a Group qualified GroupResource
+func Resource(resource string) schema.GroupResource {
+	return SchemeGroupVersion.WithResource(resource).GroupResource()
+}
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&OrderStep{},
+		&OrderStepList{},
+		&CronOrderStep{},
+		&CronOrderStepList{},
+		&OrderStepRun{},
+		&OrderStepRunList{},
+		&OrderStepTemplate{},
+		&OrderStepTemplateList{},
+		&ClusterOrderStepTemplate{},
+		&ClusterOrderStepTemplateList{},
+	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
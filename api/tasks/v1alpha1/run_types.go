@@ -0,0 +1,65 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OrderStepRun is one execution of an OrderStep, the OrderStep is run again by creating another one.
+// Every run has its own pods, named after the run, and its own status.
+type OrderStepRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec OrderStepRunSpec `json:"spec,omitempty"`
+	// Status.ResolvedSpec is the spec of the OrderStep when the run started, later changes
+	// of the OrderStep do not affect the run.
+	Status OrderStepStatus `json:"status,omitempty"`
+}
+
+type OrderStepRunSpec struct {
+	// OrderStepRef is the OrderStep executed, in the namespace of the run.
+	OrderStepRef OrderStepRef `json:"orderStepRef"`
+
+	// Params bind the params of the OrderStep for this run, the others keep their default.
+	Params []ParamBinding `json:"params,omitempty"`
+
+	// Status cancels or pauses the run like the status of an OrderStep.
+	Status OrderStepSpecStatus `json:"status,omitempty"`
+
+	// Approvals sign off the approval steps of this run.
+	Approvals []Approval `json:"approvals,omitempty"`
+}
+
+type OrderStepRef struct {
+	Name string `json:"name"`
+}
+
+type RunPolicy string
+
+const (
+	// RunPolicyAuto runs the OrderStep once by itself.
+	RunPolicyAuto RunPolicy = "Auto"
+	// RunPolicyManual makes the OrderStep a definition, it is only executed by its OrderStepRuns.
+	RunPolicyManual RunPolicy = "Manual"
+)
+
+const (
+	// OrderStepLabel is set on the OrderStepRuns to the name of the OrderStep they run, it lists the runs
+	// of a definition. Its key differs from OrderStepNameAnnotation which leads from the pods to their OrderStep.
+	OrderStepLabel = OrderTaskGroup + "/order-step-definition"
+	// OrderStepRunNameAnnotation is set on the pods running the steps of an OrderStepRun, it leads
+	// from the pods of a Job back to their run.
+	OrderStepRunNameAnnotation = OrderTaskGroup + "/order-step-run"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type OrderStepRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []OrderStepRun `json:"items"`
+}
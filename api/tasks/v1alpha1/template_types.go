@@ -0,0 +1,83 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OrderStepTemplate holds the steps shared by the OrderSteps of its namespace referencing it.
+type OrderStepTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StepTemplateSpec `json:"spec,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterOrderStepTemplate holds the steps shared by the OrderSteps of every namespace referencing it.
+type ClusterOrderStepTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StepTemplateSpec `json:"spec,omitempty"`
+}
+
+type StepTemplateSpec struct {
+	Steps   []Step `json:"steps"`
+	Finally []Step `json:"finally,omitempty"`
+
+	// Params may leave out their default, every OrderStep referencing the template has to bind them.
+	Params []ParamSpec `json:"params,omitempty"`
+
+	ParallelGroups []ParallelGroup        `json:"parallelGroups,omitempty"`
+	Workspaces     []WorkspaceDeclaration `json:"workspaces,omitempty"`
+
+	// PodTemplate and ActiveDeadline are the defaults of the OrderSteps which do not set their own.
+	PodTemplate    *PodTemplate     `json:"podTemplate,omitempty"`
+	ActiveDeadline *metav1.Duration `json:"activeDeadline,omitempty"`
+}
+
+type TemplateKind string
+
+const (
+	TemplateKindNamespaced TemplateKind = "OrderStepTemplate"
+	TemplateKindCluster    TemplateKind = "ClusterOrderStepTemplate"
+)
+
+// TemplateRef takes the steps of an OrderStep from a template.
+type TemplateRef struct {
+	// Kind is OrderStepTemplate, looked up in the namespace of the OrderStep, by default.
+	Kind TemplateKind `json:"kind,omitempty"`
+	Name string       `json:"name"`
+
+	// Params bind the params of the template, they take the place of their defaults.
+	Params []ParamBinding `json:"params,omitempty"`
+}
+
+type ParamBinding struct {
+	Name  string     `json:"name"`
+	Value ParamValue `json:"value"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type OrderStepTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []OrderStepTemplate `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type ClusterOrderStepTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterOrderStepTemplate `json:"items"`
+}
@@ -0,0 +1,300 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OrderStep runs its steps one after another as the containers of a single pod.
+type OrderStep struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OrderStepSpec   `json:"spec,omitempty"`
+	Status OrderStepStatus `json:"status,omitempty"`
+}
+
+type OrderStepSpec struct {
+	// Steps are required unless they are taken from the template.
+	Steps []Step `json:"steps,omitempty"`
+
+	// TemplateRef takes the steps, finally steps, params, parallel groups and workspaces from a template,
+	// the OrderStep does not declare them itself then.
+	TemplateRef *TemplateRef `json:"templateRef,omitempty"`
+
+	// Finally steps run after the steps, whether they succeeded, failed or timed out.
+	// They can not run once the activeDeadline killed the pod.
+	Finally []Step `json:"finally,omitempty"`
+
+	// Params are declared with their type and default, steps refer to them as $(params.<name>)
+	// in their image, command, args, env and when expressions.
+	Params []ParamSpec `json:"params,omitempty"`
+
+	// ParallelGroups configure the groups named by the parallel field of the steps,
+	// a group without one completes once all its members finished.
+	ParallelGroups []ParallelGroup `json:"parallelGroups,omitempty"`
+
+	// Workspaces are mounted into the steps listing them, a step refers to the
+	// path it mounted a workspace at as $(workspaces.<name>.path).
+	Workspaces []WorkspaceDeclaration `json:"workspaces,omitempty"`
+
+	// ExecutionMode defaults to pod, all the steps then run as containers of a single pod.
+	ExecutionMode ExecutionMode `json:"executionMode,omitempty"`
+
+	// Job configures the Job running the steps in job mode.
+	Job *JobOptions `json:"job,omitempty"`
+
+	// PodTemplate is merged into the pod running the steps.
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+
+	// Status set to Cancelled terminates the running steps and skips the others, set to Paused
+	// no further step starts until it is cleared. A cancelled OrderStep can not be resumed.
+	Status OrderStepSpecStatus `json:"status,omitempty"`
+
+	// ActiveDeadline bounds the whole OrderStep, the pod is killed once it is exceeded.
+	ActiveDeadline *metav1.Duration `json:"activeDeadline,omitempty"`
+
+	// Approvals sign off the approval steps, the order does not move onto an approval step before.
+	Approvals []Approval `json:"approvals,omitempty"`
+
+	// RunPolicy defaults to Auto, the OrderStep runs once by itself. A Manual OrderStep is a definition
+	// executed by every OrderStepRun referring to it, its template is resolved when each run starts.
+	RunPolicy RunPolicy `json:"runPolicy,omitempty"`
+
+	// RunHistoryLimit is how many finished OrderStepRuns of the OrderStep are kept, 10 by default.
+	RunHistoryLimit *int32 `json:"runHistoryLimit,omitempty"`
+
+	// TTLSecondsAfterFinished is how long the pods of the finished OrderStep, or of each of its runs, are kept.
+	// The status stays with the tail of the step logs. The default of the operator applies when unset,
+	// the pods are kept without either.
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// DeleteAfterTTL deletes the OrderStep, or the run, itself once the ttl expired instead of only its pods.
+	DeleteAfterTTL *bool `json:"deleteAfterTTL,omitempty"`
+}
+
+// Step is a container executed in order by the entrypoint.
+type Step struct {
+	corev1.Container `json:",inline"`
+
+	// Script is run instead of the command, it starts with a shebang or is run by sh -e.
+	// The args are passed to it.
+	Script string `json:"script,omitempty"`
+
+	// Timeout bounds the step, the entrypoint sends SIGTERM once it is exceeded
+	// and SIGKILL after a grace period.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Retries is how many more times the entrypoint runs the step after it exited non-zero, at most 9.
+	Retries int32 `json:"retries,omitempty"`
+	// Backoff is the delay before the first retry, it doubles after every attempt up to 5m.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+
+	// OnError decides whether the chain goes on once the step failed, defaults to stop.
+	OnError OnErrorType `json:"onError,omitempty"`
+
+	// RunAfter names the steps this one waits for. Once a step declares it the steps form a graph:
+	// those without it start right away and independent ones run concurrently in the pod.
+	// Only allowed on the steps, the finally steps still run one after another.
+	RunAfter []string `json:"runAfter,omitempty"`
+
+	// Parallel names the group of adjacent steps this one belongs to, the members of a group
+	// start together once it is reached. Only allowed on the steps, not with runAfter.
+	Parallel string `json:"parallel,omitempty"`
+
+	// When guards the step, it is skipped unless every expression holds once the step is reached.
+	When []WhenExpression `json:"when,omitempty"`
+
+	// Results the step writes to $(results.<name>.path), the later steps refer to
+	// them as $(steps.<step>.results.<name>), which is empty when the step did not write it.
+	Results []StepResult `json:"results,omitempty"`
+
+	Workspaces []WorkspaceUsage `json:"workspaces,omitempty"`
+
+	// PodTemplate overrides the fields it sets in the podTemplate of the OrderStep
+	// for the pod of this step, it is only allowed in podPerStep mode.
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+
+	// Approval makes the step a gate waiting for a user to approve it instead of running a command.
+	Approval *ApprovalGate `json:"approval,omitempty"`
+}
+
+type StepResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type ParallelGroup struct {
+	Name string `json:"name"`
+	// Completion defaults to all, with any the steps after the group start
+	// as soon as one of its members finished while the others keep running.
+	Completion GroupCompletion `json:"completion,omitempty"`
+}
+
+type GroupCompletion string
+
+const (
+	GroupCompletionAll GroupCompletion = "all"
+	GroupCompletionAny GroupCompletion = "any"
+)
+
+type WhenOperator string
+
+const (
+	WhenOperatorIn    WhenOperator = "in"
+	WhenOperatorNotIn WhenOperator = "notin"
+)
+
+// WhenExpression holds when Input, after variable substitution, is (or is not) one of Values.
+// $(params.<name>) refers to a string param, $(steps.<name>.exitCode),
+// $(steps.<name>.reason) and $(steps.<name>.results.<result>) refer to a step that already ran.
+type WhenExpression struct {
+	Input    string       `json:"input"`
+	Operator WhenOperator `json:"operator"`
+	Values   []string     `json:"values"`
+}
+
+type ExecutionMode string
+
+const (
+	ExecutionModePod ExecutionMode = "pod"
+	// ExecutionModePodPerStep runs every step in its own pod once the previous one finished,
+	// the steps only share data through results and PVC backed workspaces.
+	ExecutionModePodPerStep ExecutionMode = "podPerStep"
+	// ExecutionModeJob runs the pod of the steps through a Job, a failed pod
+	// is run again from the first step within the backoffLimit.
+	ExecutionModeJob ExecutionMode = "job"
+)
+
+// JobOptions are set on the Job running the steps in job mode.
+type JobOptions struct {
+	// BackoffLimit defaults to 0, the steps run once.
+	BackoffLimit            *int32 `json:"backoffLimit,omitempty"`
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+type OnErrorType string
+
+const (
+	OnErrorStop     OnErrorType = "stop"
+	OnErrorContinue OnErrorType = "continue"
+)
+
+const (
+	// PinImageDigestAnnotation set to "false" keeps the step images on their tags
+	// instead of pinning them to the digest resolved at admission.
+	PinImageDigestAnnotation = OrderTaskGroup + "/pin-image-digest"
+	// SequentialResourcesAnnotation set to "false" keeps the requests of every step as declared
+	// instead of reserving only the largest one, the steps running one at a time.
+	SequentialResourcesAnnotation = OrderTaskGroup + "/sequential-resources"
+	// OrderStepNameAnnotation is set on the pods running the steps, it leads from the
+	// pods of a Job back to their OrderStep.
+	OrderStepNameAnnotation = OrderTaskGroup + "/order-step"
+)
+
+type OrderStepSpecStatus string
+
+const (
+	OrderStepSpecStatusCancelled OrderStepSpecStatus = "Cancelled"
+	OrderStepSpecStatusPaused    OrderStepSpecStatus = "Paused"
+)
+
+type OrderStepPhase string
+
+const (
+	OrderStepPending   OrderStepPhase = "Pending"
+	OrderStepRunning   OrderStepPhase = "Running"
+	OrderStepSucceeded OrderStepPhase = "Succeeded"
+	OrderStepFailed    OrderStepPhase = "Failed"
+	OrderStepCancelled OrderStepPhase = "Cancelled"
+	OrderStepPaused    OrderStepPhase = "Paused"
+)
+
+const (
+	// ConditionSucceeded is Unknown while the steps are running and
+	// becomes True or False once the OrderStep finished.
+	ConditionSucceeded = "Succeeded"
+	// ConditionAwaitingApproval is True while the order is held before an approval step.
+	ConditionAwaitingApproval = "AwaitingApproval"
+)
+
+const (
+	StepReasonWaiting   = "Waiting"
+	StepReasonRunning   = "Running"
+	StepReasonCompleted = "Completed"
+	StepReasonFailed    = "Failed"
+	StepReasonTimedOut  = "TimedOut"
+	StepReasonSkipped   = "Skipped"
+	StepReasonCancelled = "Cancelled"
+
+	StepReasonAwaitingApproval = "AwaitingApproval"
+)
+
+type OrderStepStatus struct {
+	// ObservedGeneration is the generation of the spec the status was computed from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	Phase OrderStepPhase `json:"phase,omitempty"`
+
+	// CurrentStep is the name of the step being executed.
+	CurrentStep string `json:"currentStep,omitempty"`
+
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	Steps   []StepStatus `json:"steps,omitempty"`
+	Finally []StepStatus `json:"finally,omitempty"`
+
+	// ResolvedSpec is the spec resolved from the templateRef when the OrderStep was first reconciled,
+	// later changes of the template do not affect the OrderStep.
+	ResolvedSpec *OrderStepSpec `json:"resolvedSpec,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type StepStatus struct {
+	Name       string       `json:"name"`
+	Container  string       `json:"container"`
+	StartedAt  *metav1.Time `json:"startedAt,omitempty"`
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+	ExitCode   *int32       `json:"exitCode,omitempty"`
+	Reason     string       `json:"reason,omitempty"`
+
+	// Attempts lists every execution of the step when it has been retried.
+	Attempts []StepAttempt `json:"attempts,omitempty"`
+
+	Results []StepResultValue `json:"results,omitempty"`
+
+	// ApprovedBy and ApprovedAt record the approval of an approval step.
+	ApprovedBy string       `json:"approvedBy,omitempty"`
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+
+	// Logs is the tail of the step output, recorded before its pod is garbage collected.
+	Logs string `json:"logs,omitempty"`
+}
+
+type StepResultValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type StepAttempt struct {
+	StartedAt metav1.Time     `json:"startedAt"`
+	Duration  metav1.Duration `json:"duration"`
+	ExitCode  int32           `json:"exitCode"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type OrderStepList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []OrderStep `json:"items"`
+}
@@ -0,0 +1,32 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// WorkspaceDeclaration is a volume shared by the steps requesting it, exactly one
+// of its sources must be set.
+type WorkspaceDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// MountPath defaults to /workspace/<name>, a step may mount it elsewhere.
+	MountPath string `json:"mountPath,omitempty"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+	// VolumeClaimTemplate is created as a PVC owned by the OrderStep before the pod.
+	VolumeClaimTemplate *corev1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty"`
+	ConfigMap           *corev1.ConfigMapVolumeSource `json:"configMap,omitempty"`
+	Secret              *corev1.SecretVolumeSource    `json:"secret,omitempty"`
+	EmptyDir            *corev1.EmptyDirVolumeSource  `json:"emptyDir,omitempty"`
+}
+
+// WorkspaceUsage mounts a declared workspace into a step.
+type WorkspaceUsage struct {
+	Name string `json:"name"`
+	// MountPath overrides the one of the workspace.
+	MountPath string `json:"mountPath,omitempty"`
+	SubPath   string `json:"subPath,omitempty"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
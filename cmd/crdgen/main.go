@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/controllers/cron_order_step"
+	"github.com/daicheng123/ordertask-operator/controllers/order_step_run"
+	"github.com/daicheng123/ordertask-operator/controllers/order_task"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"os"
+	"sigs.k8s.io/yaml"
+)
+
+// crdgen prints the CRDs installed by the operator, so they can be applied by hand
+// on clusters where the operator is not allowed to manage CRDs.
+func main() {
+	crds := []*apiextensionsv1.CustomResourceDefinition{
+		order_task.OrderStepCustomResourceDefinition(),
+		order_task.OrderStepTemplateCustomResourceDefinition(),
+		order_task.ClusterOrderStepTemplateCustomResourceDefinition(),
+		cron_order_step.CronOrderStepCustomResourceDefinition(),
+		order_step_run.OrderStepRunCustomResourceDefinition(),
+	}
+	for i, crd := range crds {
+		out, err := yaml.Marshal(crd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error marshalling crd: %s", err)
+			os.Exit(-1)
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(out))
+	}
+}
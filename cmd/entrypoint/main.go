@@ -1,12 +1,20 @@
 package main
 
 import (
+	"errors"
 	"github.com/daicheng123/ordertask-operator/cmd/entrypoint/utils"
 	"os"
 )
 
 func main() {
 	if err := utils.RootCmd.Execute(); err != nil {
+		var exitErr *utils.ExitError
+		switch {
+		case errors.Is(err, utils.ErrStepTimedOut):
+			os.Exit(utils.TimedOutExitCode)
+		case errors.As(err, &exitErr) && exitErr.ExitCode > 0:
+			os.Exit(exitErr.ExitCode)
+		}
 		os.Exit(-1)
 	}
 }
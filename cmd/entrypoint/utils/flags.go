@@ -2,11 +2,20 @@ package utils
 
 import (
 	"errors"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/pkg/termination"
 	"time"
 )
 
 const (
 	defaultScanInterval = 20
+	defaultGracePeriod  = 10 * time.Second
+	defaultBackoff      = time.Second
+	// maxBackoff stops the doubling of the backoff between the attempts
+	maxBackoff = 5 * time.Minute
+
+	onErrorStop     = "stop"
+	onErrorContinue = "continue"
 )
 
 type EntryFlags struct {
@@ -15,8 +24,20 @@ type EntryFlags struct {
 	out             string
 	command         string
 	quitContent     string
+	cancelContent   string
 	encodeFile      string
 	scanInterval    time.Duration
+	timeout         time.Duration
+	gracePeriod     time.Duration
+	retries         int
+	backoff         time.Duration
+	terminationLog  string
+	resultsRoot     string
+	stepName        string
+	results         []string
+	onError         string
+	readySet        bool
+	approval        bool
 }
 
 func (ef *EntryFlags) validate() error {
@@ -24,10 +45,38 @@ func (ef *EntryFlags) validate() error {
 		return errors.New("wait file can't be empty!")
 	}
 
-	if len(ef.command) == 0 {
+	if len(ef.command) == 0 && !ef.approval {
 		return errors.New("command  can't be empty!")
 	}
 
+	if ef.timeout < 0 {
+		return errors.New("timeout can't be negative!")
+	}
+
+	if ef.retries < 0 {
+		return errors.New("retries can't be negative!")
+	}
+
+	if ef.retries >= termination.MaxAttempts {
+		return fmt.Errorf("retries must be less than %d!", termination.MaxAttempts)
+	}
+
+	if ef.backoff <= 0 {
+		ef.backoff = defaultBackoff
+	}
+
+	if ef.gracePeriod <= 0 {
+		ef.gracePeriod = defaultGracePeriod
+	}
+
+	if ef.onError != onErrorStop && ef.onError != onErrorContinue {
+		return errors.New("on-error must be stop or continue!")
+	}
+
+	if len(ef.results) != 0 && (len(ef.resultsRoot) == 0 || len(ef.stepName) == 0) {
+		return errors.New("results need both results-root and step-name!")
+	}
+
 	if ef.scanInterval == 0 {
 		ef.scanInterval = defaultScanInterval * time.Millisecond
 	}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"github.com/daicheng123/ordertask-operator/pkg/termination"
+	"testing"
+	"time"
+)
+
+func TestEntryFlagsValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(ef *EntryFlags)
+		wantErr     bool
+		wantBackoff time.Duration
+	}{
+		{name: "default backoff", modify: func(ef *EntryFlags) {}, wantBackoff: defaultBackoff},
+		{name: "backoff", modify: func(ef *EntryFlags) { ef.backoff = time.Minute }, wantBackoff: time.Minute},
+		{name: "retries", modify: func(ef *EntryFlags) { ef.retries = termination.MaxAttempts - 1 }, wantBackoff: defaultBackoff},
+		{name: "negative retries", modify: func(ef *EntryFlags) { ef.retries = -1 }, wantErr: true},
+		{name: "too many retries", modify: func(ef *EntryFlags) { ef.retries = termination.MaxAttempts }, wantErr: true},
+		{name: "negative timeout", modify: func(ef *EntryFlags) { ef.timeout = -time.Second }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ef := &EntryFlags{waitFile: "/order/wait", command: "true", onError: onErrorStop}
+			tt.modify(ef)
+			err := ef.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected an error: %t, got %v", tt.wantErr, err)
+			}
+			if err == nil && ef.backoff != tt.wantBackoff {
+				t.Errorf("expected a backoff of %s, got %s", tt.wantBackoff, ef.backoff)
+			}
+		})
+	}
+}
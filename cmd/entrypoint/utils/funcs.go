@@ -1,14 +1,48 @@
 package utils
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/pkg/termination"
 	"golang.org/x/sys/execabs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
-func watchWaitFile() error {
+const (
+	// TimedOutExitCode is the exit code of a step killed after its timeout, the same as coreutils timeout.
+	TimedOutExitCode = 124
+	// reasonTimedOut is written to the termination log so the controller can tell a timeout from a failure.
+	reasonTimedOut  = "TimedOut"
+	reasonFailed    = "Failed"
+	reasonSkipped   = "Skipped"
+	reasonCancelled = "Cancelled"
+)
+
+var (
+	ErrStepTimedOut  = errors.New("step timed out")
+	ErrStepCancelled = errors.New("step cancelled")
+)
+
+// ExitError is returned once the command failed, the entrypoint exits with the exit code of
+// the command so that the container reports it rather than the one of the entrypoint.
+type ExitError struct {
+	ExitCode int
+	Attempts int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("step exited with code %d after %d attempts", e.ExitCode, e.Attempts)
+}
+
+// watchWaitFile blocks until the wait file holds the content of this step, skip is true when
+// the order moved past it or the task quit, the step must then exit without running.
+func watchWaitFile() (skip bool, err error) {
 	ticker := time.NewTicker(entryFlags.scanInterval)
 	defer ticker.Stop()
 	for {
@@ -16,20 +50,125 @@ func watchWaitFile() error {
 		f, err := os.Stat(entryFlags.waitFile)
 		if err == nil {
 			if f.IsDir() {
-				return errors.New("wait file cloud not be directory!")
+				return false, errors.New("wait file cloud not be directory!")
 			}
-			return nil
+			if len(entryFlags.waitFileContent) == 0 {
+				return false, nil
+			}
+			content, err := os.ReadFile(entryFlags.waitFile)
+			if err != nil {
+				return false, err
+			}
+			current := string(bytes.TrimSpace(content))
+			if hasCancelToken(current) {
+				return true, nil
+			}
+			if entryFlags.readySet {
+				if run, skip := readySetState(current); run || skip {
+					return skip, nil
+				}
+				continue
+			}
+			if current == entryFlags.waitFileContent {
+				return false, nil
+			}
+			if current == entryFlags.quitContent || orderPassed(current, entryFlags.waitFileContent) {
+				return true, nil
+			}
+			continue
 		} else if errors.Is(err, os.ErrNotExist) {
 			continue
 		} else {
-			return err
+			return false, err
+		}
+	}
+}
+
+func orderPassed(current, wait string) bool {
+	c, err := strconv.Atoi(current)
+	if err != nil {
+		return false
+	}
+	w, err := strconv.Atoi(wait)
+	if err != nil {
+		return false
+	}
+	return c > w
+}
+
+// readySetState looks up the step in the comma separated orders released by the controller
+// when the steps form a graph, an order prefixed with ! is released to be skipped.
+func readySetState(content string) (run bool, skip bool) {
+	for _, token := range strings.Split(content, ",") {
+		switch token {
+		case entryFlags.waitFileContent:
+			return true, false
+		case "!" + entryFlags.waitFileContent, entryFlags.quitContent:
+			return false, true
 		}
 	}
+	return false, false
+}
+
+// skipStep leaves the step without running its command.
+func skipStep() error {
+	reason := reasonSkipped
+	if isCancelled() {
+		reason = reasonCancelled
+	}
+	_ = writeTerminationLog(&termination.Message{Reason: reason})
+	return nil
+}
+
+// approveStep leaves an approval step completed, it has no command to run.
+func approveStep() error {
+	_ = writeTerminationLog(&termination.Message{})
+	return nil
+}
+
+// isCancelled reports whether the wait file tells the task was cancelled.
+func isCancelled() bool {
+	content, err := os.ReadFile(entryFlags.waitFile)
+	if err != nil {
+		return false
+	}
+	return hasCancelToken(string(bytes.TrimSpace(content)))
+}
+
+func hasCancelToken(content string) bool {
+	for _, token := range strings.Split(content, ",") {
+		if token == entryFlags.cancelContent {
+			return true
+		}
+	}
+	return false
+}
+
+// watchCancel polls the wait file while the command runs, the returned channel is closed
+// once the task was cancelled.
+func watchCancel(stop <-chan struct{}) <-chan struct{} {
+	cancelled := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(entryFlags.scanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if isCancelled() {
+					close(cancelled)
+					return
+				}
+			}
+		}
+	}()
+	return cancelled
 }
 
-func execCmdAndArgs(args []string) error {
+func execCmdAndArgs(args []string) (err error) {
 	var logFile *os.File
-	if entryFlags.out == "" {
+	if entryFlags.out == "" || entryFlags.out == "stdout" {
 		logFile = os.Stdout
 	} else {
 		outfilePath := filepath.Join(getWorkDir(), entryFlags.out)
@@ -40,10 +179,137 @@ func execCmdAndArgs(args []string) error {
 		logFile = lf
 		defer logFile.Close()
 	}
+
+	msg := &termination.Message{}
+	defer func() {
+		msg.Results = collectResults()
+		writeErr := writeTerminationLog(msg)
+		if !errors.Is(writeErr, termination.ErrMessageTooLarge) {
+			return
+		}
+		// the results are dropped so that the attempts still reach the controller, the step fails for them
+		fmt.Fprintf(logFile, "entrypoint: the results do not fit in the termination log, %s\n", writeErr)
+		msg.Reason, msg.Results = reasonFailed, nil
+		_ = writeTerminationLog(msg)
+		if err == nil && entryFlags.onError != onErrorContinue {
+			err = writeErr
+		}
+	}()
+
+	backoff := entryFlags.backoff
+	for i := 0; ; i++ {
+		attempt, err := runAttempt(logFile, args)
+		if err != nil {
+			return err
+		}
+		msg.Attempts = append(msg.Attempts, attempt)
+		if attempt.Cancelled {
+			msg.Reason = reasonCancelled
+			return ErrStepCancelled
+		}
+		if attempt.ExitCode == 0 {
+			return nil
+		}
+		// no more attempts once the task was cancelled
+		if isCancelled() {
+			msg.Reason = reasonCancelled
+			return ErrStepCancelled
+		}
+		if i >= entryFlags.retries {
+			break
+		}
+		fmt.Fprintf(logFile, "entrypoint: attempt %d exited with %d, retrying in %s\n", i+1, attempt.ExitCode, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+
+	last := msg.Attempts[len(msg.Attempts)-1]
+	if last.TimedOut {
+		msg.Reason = reasonTimedOut
+		err = fmt.Errorf("%w after %s", ErrStepTimedOut, entryFlags.timeout)
+	} else {
+		msg.Reason = reasonFailed
+		err = &ExitError{ExitCode: int(last.ExitCode), Attempts: len(msg.Attempts)}
+	}
+	// the failure is only reported, the container succeeds so that the pod does not fail for it
+	if entryFlags.onError == onErrorContinue {
+		fmt.Fprintf(logFile, "entrypoint: %s, continuing\n", err)
+		return nil
+	}
+	return err
+}
+
+// runAttempt executes the command once, err is only set when it could not be started.
+func runAttempt(logFile *os.File, args []string) (termination.Attempt, error) {
+	attempt := termination.Attempt{StartedAt: time.Now()}
 	exec := execabs.Command(entryFlags.command, args...)
 	exec.Stdout = logFile
 	exec.Stderr = logFile
-	return exec.Run()
+	if err := exec.Start(); err != nil {
+		return attempt, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- exec.Wait()
+	}()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	cancelled := watchCancel(stop)
+	// a nil channel never fires, there is no timeout
+	var timeout <-chan time.Time
+	if entryFlags.timeout != 0 {
+		timer := time.NewTimer(entryFlags.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var waitErr error
+	select {
+	case waitErr = <-done:
+	case <-timeout:
+		attempt.TimedOut = true
+		waitErr = terminate(exec, done)
+	case <-cancelled:
+		attempt.Cancelled = true
+		waitErr = terminate(exec, done)
+	}
+
+	attempt.Duration = time.Since(attempt.StartedAt)
+	attempt.ExitCode = int32(exec.ProcessState.ExitCode())
+	if attempt.TimedOut {
+		attempt.ExitCode = TimedOutExitCode
+	}
+	var exitErr *execabs.ExitError
+	if waitErr != nil && !errors.As(waitErr, &exitErr) {
+		return attempt, waitErr
+	}
+	return attempt, nil
+}
+
+// terminate gives the command a chance to clean up before it is killed.
+func terminate(exec *execabs.Cmd, done <-chan error) error {
+	_ = exec.Process.Signal(syscall.SIGTERM)
+	grace := time.NewTimer(entryFlags.gracePeriod)
+	defer grace.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-grace.C:
+		_ = exec.Process.Kill()
+		return <-done
+	}
+}
+
+func writeTerminationLog(msg *termination.Message) error {
+	if len(entryFlags.terminationLog) == 0 {
+		return nil
+	}
+	return termination.Write(entryFlags.terminationLog, msg)
 }
 
 func getWorkDir() string {
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"errors"
+	"github.com/daicheng123/ordertask-operator/pkg/termination"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHasCancelToken(t *testing.T) {
+	setEntryFlags(t, &EntryFlags{cancelContent: "-2"})
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{content: "2"},
+		{content: "-1"},
+		{content: "-2", want: true},
+		// the ready set of a graph keeps the released steps
+		{content: "1,2,!3,-2", want: true},
+		{content: "1,-22"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			if got := hasCancelToken(tt.content); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecCmdAndArgsTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		onError string
+		wantErr error
+	}{
+		{name: "terminated", script: "exec sleep 10", onError: onErrorStop, wantErr: ErrStepTimedOut},
+		// SIGTERM is ignored, the command is killed once the grace period passed
+		{name: "killed", script: "trap '' TERM; while true; do sleep 0.01; done", onError: onErrorStop, wantErr: ErrStepTimedOut},
+		{name: "allowed to fail", script: "exec sleep 10", onError: onErrorContinue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			waitFile := filepath.Join(dir, "wait")
+			if err := os.WriteFile(waitFile, []byte("1"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			setEntryFlags(t, &EntryFlags{
+				waitFile:       waitFile,
+				cancelContent:  "-2",
+				command:        "sh",
+				scanInterval:   10 * time.Millisecond,
+				timeout:        100 * time.Millisecond,
+				gracePeriod:    100 * time.Millisecond,
+				backoff:        defaultBackoff,
+				onError:        tt.onError,
+				terminationLog: filepath.Join(dir, "termination-log"),
+			})
+
+			start := time.Now()
+			err := execCmdAndArgs([]string{"-c", tt.script})
+			if !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("expected the command to be stopped after its timeout, it ran %s", elapsed)
+			}
+
+			data, err := os.ReadFile(entryFlags.terminationLog)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := termination.Parse(string(data))
+			if msg == nil || msg.Reason != reasonTimedOut || len(msg.Attempts) != 1 {
+				t.Fatalf("expected a TimedOut termination message with one attempt, got %s", data)
+			}
+			if attempt := msg.Attempts[0]; attempt.ExitCode != TimedOutExitCode || !attempt.TimedOut {
+				t.Errorf("expected the attempt to time out with %d, got %+v", TimedOutExitCode, attempt)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	stepsVariablePrefix = "steps."
+	resultsVariablePart = ".results."
+)
+
+// prepareResultsDir creates the directory the step writes its results to.
+func prepareResultsDir() error {
+	if len(entryFlags.resultsRoot) == 0 || len(entryFlags.stepName) == 0 {
+		return nil
+	}
+	return os.MkdirAll(filepath.Join(entryFlags.resultsRoot, entryFlags.stepName), 0777)
+}
+
+// resolveStepResults substitutes $(steps.<name>.results.<result>) with what the earlier
+// steps wrote, in the command, its args and the environment of the step. A result the step
+// did not write, e.g. as it was skipped, is empty.
+func resolveStepResults(args []string) ([]string, error) {
+	if len(entryFlags.resultsRoot) == 0 {
+		return args, nil
+	}
+	vars := make(map[string]string)
+	collect := func(s string) error {
+		for _, name := range substitution_util.References(s) {
+			if _, ok := vars[name]; ok || !strings.HasPrefix(name, stepsVariablePrefix) {
+				continue
+			}
+			step, result, ok := strings.Cut(strings.TrimPrefix(name, stepsVariablePrefix), resultsVariablePart)
+			if !ok {
+				continue
+			}
+			value, err := os.ReadFile(filepath.Join(entryFlags.resultsRoot, step, result))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("result %s of step %s is not available: %w", result, step, err)
+			}
+			vars[name] = strings.TrimSuffix(string(value), "\n")
+		}
+		return nil
+	}
+
+	if err := collect(entryFlags.command); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := collect(arg); err != nil {
+			return nil, err
+		}
+	}
+	for _, env := range os.Environ() {
+		if err := collect(env); err != nil {
+			return nil, err
+		}
+	}
+	if len(vars) == 0 {
+		return args, nil
+	}
+
+	entryFlags.command = substitution_util.Replace(entryFlags.command, vars)
+	for _, env := range os.Environ() {
+		if key, value, ok := strings.Cut(env, "="); ok && strings.Contains(value, "$(") {
+			_ = os.Setenv(key, substitution_util.Replace(value, vars))
+		}
+	}
+	return substitution_util.ReplaceAll(args, vars), nil
+}
+
+// collectResults reads the declared results the step wrote, the missing ones are left out.
+func collectResults() map[string]string {
+	if len(entryFlags.results) == 0 {
+		return nil
+	}
+	results := make(map[string]string, len(entryFlags.results))
+	for _, result := range entryFlags.results {
+		value, err := os.ReadFile(filepath.Join(entryFlags.resultsRoot, entryFlags.stepName, result))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "entrypoint: failed to read result %s: %s\n", result, err)
+			}
+			continue
+		}
+		results[result] = strings.TrimSuffix(string(value), "\n")
+	}
+	return results
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setEntryFlags replaces the flags of the entrypoint for the test.
+func setEntryFlags(t *testing.T, ef *EntryFlags) {
+	previous := entryFlags
+	entryFlags = ef
+	t.Cleanup(func() { entryFlags = previous })
+}
+
+func writeResult(t *testing.T, root, step, name, value string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, step), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, step, name), []byte(value), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveStepResults(t *testing.T) {
+	root := t.TempDir()
+	writeResult(t, root, "build", "digest", "sha256:1\n")
+	setEntryFlags(t, &EntryFlags{resultsRoot: root, command: "echo $(steps.build.results.digest)"})
+	t.Setenv("DIGEST", "$(steps.build.results.digest)")
+
+	args, err := resolveStepResults([]string{"$(steps.build.results.digest)", "$(steps.lint.results.report)", "$(params.env)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a step that did not write its result, e.g. as it was skipped, leaves it empty
+	want := []string{"sha256:1", "", "$(params.env)"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("expected args %v, got %v", want, args)
+	}
+	if entryFlags.command != "echo sha256:1" {
+		t.Errorf("expected the command to be resolved, got %s", entryFlags.command)
+	}
+	if env := os.Getenv("DIGEST"); env != "sha256:1" {
+		t.Errorf("expected the env to be resolved, got %s", env)
+	}
+}
+
+func TestCollectResults(t *testing.T) {
+	root := t.TempDir()
+	writeResult(t, root, "build", "digest", "sha256:1\n")
+	writeResult(t, root, "build", "undeclared", "1")
+	setEntryFlags(t, &EntryFlags{resultsRoot: root, stepName: "build", results: []string{"digest", "report"}})
+
+	want := map[string]string{"digest": "sha256:1"}
+	if got := collectResults(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
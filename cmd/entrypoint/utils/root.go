@@ -9,10 +9,22 @@ var entryFlags *EntryFlags
 func init() {
 	entryFlags = &EntryFlags{}
 	RootCmd.Flags().StringVar(&entryFlags.waitFile, "wait", "", "entrypoint --wait /var/run/1")
-	//	rootCmd.Flags().StringVar(&entryFlags.waitFileContent, "waitFileContent", "", "entrypoint --waitFileContent")
+	RootCmd.Flags().StringVar(&entryFlags.waitFileContent, "waitcontent", "", "entrypoint --waitcontent 1")
 	RootCmd.Flags().StringVar(&entryFlags.out, "out", "", "entrypoint --out /var/run/out")
 	RootCmd.Flags().StringVar(&entryFlags.command, "command", "", "entrypoint --command bash")
-	//	rootCmd.Flags().StringVar(&entryFlags.quitContent, "quit", "-1", "entrypoint --quit -2")
+	RootCmd.Flags().DurationVar(&entryFlags.timeout, "timeout", 0, "entrypoint --timeout 10m")
+	RootCmd.Flags().DurationVar(&entryFlags.gracePeriod, "grace-period", defaultGracePeriod, "entrypoint --grace-period 10s")
+	RootCmd.Flags().IntVar(&entryFlags.retries, "retries", 0, "entrypoint --retries 3")
+	RootCmd.Flags().DurationVar(&entryFlags.backoff, "backoff", defaultBackoff, "entrypoint --backoff 1s")
+	RootCmd.Flags().StringVar(&entryFlags.terminationLog, "termination-log", "/dev/termination-log", "entrypoint --termination-log /dev/termination-log")
+	RootCmd.Flags().StringVar(&entryFlags.resultsRoot, "results-root", "", "entrypoint --results-root /ordertask/results")
+	RootCmd.Flags().StringVar(&entryFlags.stepName, "step-name", "", "entrypoint --step-name build")
+	RootCmd.Flags().StringSliceVar(&entryFlags.results, "results", nil, "entrypoint --results version,digest")
+	RootCmd.Flags().StringVar(&entryFlags.onError, "on-error", onErrorStop, "entrypoint --on-error continue")
+	RootCmd.Flags().BoolVar(&entryFlags.readySet, "ready-set", false, "entrypoint --ready-set")
+	RootCmd.Flags().StringVar(&entryFlags.quitContent, "quit", "-1", "entrypoint --quit -1")
+	RootCmd.Flags().StringVar(&entryFlags.cancelContent, "cancel", "-2", "entrypoint --cancel -2")
+	RootCmd.Flags().BoolVar(&entryFlags.approval, "approval", false, "entrypoint --approval")
 	//	rootCmd.Flags().StringVar(&entryFlags.encodeFile, "encodefile", "-1", "entrypoint --encodefile /var/run/1")
 }
 
@@ -24,8 +36,21 @@ var RootCmd = &cobra.Command{
 		return entryFlags.validate()
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var err error
-		if err = watchWaitFile(); err != nil {
+		skip, err := watchWaitFile()
+		if err != nil {
+			return err
+		}
+		if skip {
+			return skipStep()
+		}
+		// the order only reaches an approval step once it has been approved
+		if entryFlags.approval {
+			return approveStep()
+		}
+		if err = prepareResultsDir(); err != nil {
+			return err
+		}
+		if args, err = resolveStepResults(args); err != nil {
 			return err
 		}
 		return execCmdAndArgs(args)
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
 	"github.com/daicheng123/ordertask-operator/cmd/ordertask/utils"
+	"github.com/daicheng123/ordertask-operator/controllers/cron_order_step"
+	"github.com/daicheng123/ordertask-operator/controllers/order_step_run"
 	"github.com/daicheng123/ordertask-operator/controllers/order_task"
+	order_task_webhook "github.com/daicheng123/ordertask-operator/webhooks/order_task"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"log"
 	"net/http"
@@ -58,16 +62,31 @@ func (o *Operator) Run() error {
 		return err
 	}
 
-	_, crdCli, apiextCli, err := utils.CreateOperatorClients(o.OperatorFlags)
+	_, apiextCli, err := utils.CreateOperatorClients(o.OperatorFlags)
 	if err != nil {
 		mgr.GetLogger().Error(err, "failed to create client sets.")
 		return err
 	}
-	reconciler, err := order_task.NewReconciler(mgr, crdCli, apiextCli)
+	gcOptions, err := utils.GCOptions()
+	if err != nil {
+		mgr.GetLogger().Error(err, "failed to read the garbage collection options.")
+		return err
+	}
+	reconciler, err := order_task.NewReconciler(mgr, apiextCli, gcOptions)
 	if err != nil {
 		mgr.GetLogger().Error(err, "failed to create reconciler.")
 		return err
 	}
+	cronReconciler, err := cron_order_step.NewReconciler(mgr, apiextCli)
+	if err != nil {
+		mgr.GetLogger().Error(err, "failed to create cron reconciler.")
+		return err
+	}
+	runReconciler, err := order_step_run.NewReconciler(mgr, apiextCli, gcOptions)
+	if err != nil {
+		mgr.GetLogger().Error(err, "failed to create run reconciler.")
+		return err
+	}
 
 	err = v1alpha1.SchemeBuilder.AddToScheme(mgr.GetScheme())
 	if err != nil {
@@ -84,11 +103,42 @@ func (o *Operator) Run() error {
 		Watches(&corev1.Pod{}, handler.Funcs{
 			UpdateFunc: reconciler.OnUpdateFunc,
 		}).
+		Watches(&batchv1.Job{}, handler.Funcs{
+			UpdateFunc: reconciler.OnUpdateFunc,
+		}).
 		Complete(reconciler); err != nil {
 		mgr.GetLogger().Error(err, "failed to set up order task controller.")
 		return err
 	}
 
+	if err = ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.CronOrderStep{}).
+		Owns(&v1alpha1.OrderStep{}).
+		Complete(cronReconciler); err != nil {
+		mgr.GetLogger().Error(err, "failed to set up cron order step controller.")
+		return err
+	}
+
+	if err = ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.OrderStepRun{}).
+		Watches(&corev1.Pod{}, handler.Funcs{
+			UpdateFunc: runReconciler.OnUpdateFunc,
+		}).
+		Watches(&batchv1.Job{}, handler.Funcs{
+			UpdateFunc: runReconciler.OnUpdateFunc,
+		}).
+		Complete(runReconciler); err != nil {
+		mgr.GetLogger().Error(err, "failed to set up order step run controller.")
+		return err
+	}
+
+	if utils.WebhooksEnabled() {
+		if err = order_task_webhook.SetupWebhookWithManager(mgr); err != nil {
+			mgr.GetLogger().Error(err, "failed to set up order task webhook.")
+			return err
+		}
+	}
+
 	err = mgr.Start(signals.SetupSignalHandler())
 	if err != nil {
 		mgr.GetLogger().Error(err, "unable to start manager.")
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"flag"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type OperatorFlags struct {
+	KubeConfig           string
+	MasterURL            string
+	MetricsAddr          string
+	ListenAddr           string
+	EnableLeaderElection bool
+}
+
+func (of *OperatorFlags) Init() {
+	flag.StringVar(&of.KubeConfig, "kubeconfig", "", "path to a kubeconfig, the in-cluster config is used without it")
+	flag.StringVar(&of.MasterURL, "master", "", "address of the api server, overrides the one of the kubeconfig")
+	flag.StringVar(&of.MetricsAddr, "metrics-addr", ":8080", "address the metrics endpoint binds to")
+	flag.StringVar(&of.ListenAddr, "listen-addr", ":8081", "address the http server binds to")
+	flag.BoolVar(&of.EnableLeaderElection, "enable-leader-election", false, "only one operator is active at a time when enabled")
+	flag.Parse()
+}
+
+// LoadKubernetesConfig builds the config from the kubeconfig flags, the in-cluster config without them.
+func LoadKubernetesConfig(flags *OperatorFlags) (*rest.Config, error) {
+	return clientcmd.BuildConfigFromFlags(flags.MasterURL, flags.KubeConfig)
+}
+
+// CreateOperatorClients creates the clientsets used besides the client of the manager.
+func CreateOperatorClients(flags *OperatorFlags) (*kubernetes.Clientset, *apiextensionsclient.Clientset, error) {
+	config, err := LoadKubernetesConfig(flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeCli, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	apiextCli, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubeCli, apiextCli, nil
+}
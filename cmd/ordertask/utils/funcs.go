@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -20,3 +23,26 @@ func GetNamespace() string {
 
 	return "default"
 }
+
+// WebhooksEnabled reports whether the admission webhooks are served, they need serving
+// certificates so they can be turned off with ENABLE_WEBHOOKS=false when running locally.
+func WebhooksEnabled() bool {
+	return os.Getenv("ENABLE_WEBHOOKS") != "false"
+}
+
+// GCOptions reads the defaults of the garbage collection of finished OrderSteps and runs: the pods are
+// deleted TTL_SECONDS_AFTER_FINISHED after they finished, with DELETE_AFTER_TTL=true the OrderSteps too.
+func GCOptions() (pod_manager.GCOptions, error) {
+	options := pod_manager.GCOptions{
+		DeleteAfterTTL: os.Getenv("DELETE_AFTER_TTL") == "true",
+	}
+	if value, ok := os.LookupEnv("TTL_SECONDS_AFTER_FINISHED"); ok && len(value) != 0 {
+		seconds, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || seconds < 0 {
+			return options, fmt.Errorf("TTL_SECONDS_AFTER_FINISHED must be a number of seconds, got %q", value)
+		}
+		ttl := int32(seconds)
+		options.TTLSecondsAfterFinished = &ttl
+	}
+	return options, nil
+}
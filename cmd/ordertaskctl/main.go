@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/daicheng123/ordertask-operator/cmd/ordertaskctl/utils"
+	"os"
+)
+
+func main() {
+	if err := utils.RootCmd.Execute(); err != nil {
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var approveCmd = &cobra.Command{
+	Use:   "approve ORDERSTEP STEP",
+	Short: "Approve an approval step of an OrderStep",
+	Long:  "Adds the approval of the step to spec.approvals, the admission webhook records who approved and when",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return approve(cmd.Context(), args[0], args[1])
+	},
+}
+
+func approve(ctx context.Context, name, stepName string) error {
+	cli, err := newClient()
+	if err != nil {
+		return err
+	}
+	ot := &v1alpha1.OrderStep{}
+	if err = cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, ot); err != nil {
+		return err
+	}
+
+	gate := false
+	for i, step := range ot.Spec.Steps {
+		if pod_manager.StepName(i, step) == stepName {
+			gate = step.Approval != nil
+			break
+		}
+	}
+	if !gate {
+		return fmt.Errorf("%s is not an approval step of OrderStep %s/%s", stepName, namespace, name)
+	}
+	for _, approval := range ot.Spec.Approvals {
+		if approval.Step == stepName {
+			fmt.Printf("step %s of OrderStep %s/%s was already approved by %s\n", stepName, namespace, name, approval.ApprovedBy)
+			return nil
+		}
+	}
+
+	patch := client.MergeFromWithOptions(ot.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	ot.Spec.Approvals = append(ot.Spec.Approvals, v1alpha1.Approval{Step: stepName})
+	if err = cli.Patch(ctx, ot, patch); err != nil {
+		return err
+	}
+	fmt.Printf("step %s of OrderStep %s/%s approved\n", stepName, namespace, name)
+	return nil
+}
+
+func newClient() (client.Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	scheme := runtime.NewScheme()
+	if err = v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(config, client.Options{Scheme: scheme})
+}
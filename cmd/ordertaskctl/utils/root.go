@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	kubeconfig string
+	namespace  string
+)
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "ordertaskctl --kubeconfig ~/.kube/config")
+	RootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "default", "ordertaskctl -n default")
+	RootCmd.AddCommand(approveCmd)
+}
+
+var RootCmd = &cobra.Command{
+	Use:          "ordertaskctl",
+	Short:        "Command line tool for OrderSteps",
+	SilenceUsage: true,
+}
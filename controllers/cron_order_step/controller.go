@@ -0,0 +1,220 @@
+package cron_order_step
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
+	"time"
+)
+
+const (
+	defaultSuccessfulHistoryLimit = 3
+	defaultFailedHistoryLimit     = 1
+)
+
+// CronOrderStepController creates the OrderSteps of the CronOrderSteps when they are due and
+// cleans up the finished ones past the history limits.
+type CronOrderStepController struct {
+	manager       manager.Manager
+	eventRecorder record.EventRecorder
+}
+
+func NewReconciler(mgr manager.Manager, apiextCli *apiextensionsclient.Clientset) (*CronOrderStepController, error) {
+	reconciler := &CronOrderStepController{
+		manager:       mgr,
+		eventRecorder: mgr.GetEventRecorderFor(v1alpha1.CronOrderStepResourceKind),
+	}
+	return reconciler, k8s_utils.CreateCustomResourceDefinition(context.Background(), apiextCli,
+		CronOrderStepCustomResourceDefinition(), mgr.GetLogger())
+}
+
+func (c *CronOrderStepController) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	cos := &v1alpha1.CronOrderStep{}
+	cli := c.manager.GetClient()
+	if err := cli.Get(ctx, req.NamespacedName, cos); err != nil {
+		if k8s_utils.IsKubernetesResourceNotExist(err) {
+			// the OrderSteps are garbage collected through their owner reference
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	active, successful, failed, err := c.getOrderSteps(ctx, cos)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	status := cos.Status.DeepCopy()
+	status.Active = nil
+	for _, ot := range active {
+		status.Active = append(status.Active, orderStepReference(ot))
+	}
+	for _, ot := range successful {
+		if t := ot.Status.CompletionTime; t != nil && (status.LastSuccessfulTime == nil || status.LastSuccessfulTime.Before(t)) {
+			status.LastSuccessfulTime = t.DeepCopy()
+		}
+	}
+	if err = c.cleanupHistory(ctx, successful, cos.Spec.SuccessfulHistoryLimit, defaultSuccessfulHistoryLimit); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err = c.cleanupHistory(ctx, failed, cos.Spec.FailedHistoryLimit, defaultFailedHistoryLimit); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if cos.Spec.Suspend != nil && *cos.Spec.Suspend {
+		return reconcile.Result{}, c.updateStatus(ctx, cos, status)
+	}
+	schedule, location, err := ParseSchedule(cos)
+	if err != nil {
+		// nothing gets better by retrying until the spec is fixed
+		c.eventRecorder.Event(cos, corev1.EventTypeWarning, "InvalidSchedule", err.Error())
+		return reconcile.Result{}, c.updateStatus(ctx, cos, status)
+	}
+
+	now := time.Now()
+	scheduledTime, next, tooMany := mostRecentScheduleTime(cos, schedule, location, now)
+	if next.IsZero() {
+		c.eventRecorder.Eventf(cos, corev1.EventTypeWarning, "InvalidSchedule", "schedule %q never fires", cos.Spec.Schedule)
+		return reconcile.Result{}, c.updateStatus(ctx, cos, status)
+	}
+	result := reconcile.Result{RequeueAfter: next.Sub(now)}
+	if tooMany {
+		c.eventRecorder.Eventf(cos, corev1.EventTypeWarning, "TooManyMissedTimes",
+			"more than %d scheduled times were missed, only the most recent one is run, set or decrease startingDeadlineSeconds", maxMissedSchedules)
+	}
+	if scheduledTime == nil {
+		return result, c.updateStatus(ctx, cos, status)
+	}
+
+	switch cos.Spec.ConcurrencyPolicy {
+	case v1alpha1.ConcurrencyPolicyForbid:
+		if len(active) != 0 {
+			// the run is only skipped, it is created once the active one finished if still in time
+			c.eventRecorder.Eventf(cos, corev1.EventTypeNormal, "AlreadyActive",
+				"skipping the run scheduled at %s, OrderStep %s is still active", scheduledTime.Format(time.RFC3339), active[0].Name)
+			return result, c.updateStatus(ctx, cos, status)
+		}
+	case v1alpha1.ConcurrencyPolicyReplace:
+		for _, ot := range active {
+			if err = cli.Delete(ctx, ot, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+				return reconcile.Result{}, err
+			}
+			c.eventRecorder.Eventf(cos, corev1.EventTypeNormal, "Replaced", "deleted active OrderStep %s", ot.Name)
+		}
+		status.Active = nil
+	}
+
+	ot, err := c.newOrderStep(cos, *scheduledTime)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err = cli.Create(ctx, ot); err != nil && !k8s_utils.IsKubernetesResourceAlreadyExistError(err) {
+		c.eventRecorder.Eventf(cos, corev1.EventTypeWarning, "FailedCreate", "failed to create OrderStep %s: %s", ot.Name, err)
+		return reconcile.Result{}, err
+	} else if err == nil {
+		c.eventRecorder.Eventf(cos, corev1.EventTypeNormal, "SuccessfulCreate", "created OrderStep %s", ot.Name)
+		status.Active = append(status.Active, orderStepReference(ot))
+	}
+	status.LastScheduleTime = &metav1.Time{Time: *scheduledTime}
+	return result, c.updateStatus(ctx, cos, status)
+}
+
+// getOrderSteps returns the OrderSteps created by the CronOrderStep, split by whether they finished.
+func (c *CronOrderStepController) getOrderSteps(ctx context.Context, cos *v1alpha1.CronOrderStep) (active, successful, failed []*v1alpha1.OrderStep, err error) {
+	list := &v1alpha1.OrderStepList{}
+	err = c.manager.GetClient().List(ctx, list, client.InNamespace(cos.Namespace),
+		client.MatchingLabels{v1alpha1.CronOrderStepLabel: cos.Name})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for i := range list.Items {
+		ot := &list.Items[i]
+		if !metav1.IsControlledBy(ot, cos) {
+			continue
+		}
+		switch ot.Status.Phase {
+		case v1alpha1.OrderStepSucceeded:
+			successful = append(successful, ot)
+		case v1alpha1.OrderStepFailed, v1alpha1.OrderStepCancelled:
+			failed = append(failed, ot)
+		default:
+			active = append(active, ot)
+		}
+	}
+	return active, successful, failed, nil
+}
+
+// cleanupHistory deletes the oldest finished OrderSteps beyond the limit.
+func (c *CronOrderStepController) cleanupHistory(ctx context.Context, finished []*v1alpha1.OrderStep, limit *int32, defaultLimit int) error {
+	keep := defaultLimit
+	if limit != nil {
+		keep = int(*limit)
+	}
+	if len(finished) <= keep {
+		return nil
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreationTimestamp.Before(&finished[j].CreationTimestamp)
+	})
+	for _, ot := range finished[:len(finished)-keep] {
+		err := c.manager.GetClient().Delete(ctx, ot, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// newOrderStep stamps out the OrderStep of the run scheduled at scheduledTime from the template.
+func (c *CronOrderStepController) newOrderStep(cos *v1alpha1.CronOrderStep, scheduledTime time.Time) (*v1alpha1.OrderStep, error) {
+	template := cos.Spec.Template.DeepCopy()
+	ot := &v1alpha1.OrderStep{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        OrderStepName(cos, scheduledTime),
+			Namespace:   cos.Namespace,
+			Labels:      template.Labels,
+			Annotations: template.Annotations,
+		},
+		Spec: template.Spec,
+	}
+	if ot.Labels == nil {
+		ot.Labels = make(map[string]string)
+	}
+	ot.Labels[v1alpha1.CronOrderStepLabel] = cos.Name
+	if ot.Annotations == nil {
+		ot.Annotations = make(map[string]string)
+	}
+	ot.Annotations[v1alpha1.ScheduledAtAnnotation] = scheduledTime.Format(time.RFC3339)
+	if err := controllerutil.SetControllerReference(cos, ot, c.manager.GetScheme()); err != nil {
+		return nil, fmt.Errorf("failed to set the owner of OrderStep %s: %w", ot.Name, err)
+	}
+	return ot, nil
+}
+
+func (c *CronOrderStepController) updateStatus(ctx context.Context, cos *v1alpha1.CronOrderStep, status *v1alpha1.CronOrderStepStatus) error {
+	if reflect.DeepEqual(cos.Status, *status) {
+		return nil
+	}
+	cos.Status = *status
+	return c.manager.GetClient().Status().Update(ctx, cos)
+}
+
+func orderStepReference(ot *v1alpha1.OrderStep) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: v1alpha1.OrderTaskApiVersionGroup,
+		Kind:       v1alpha1.OrderTaskResourceKind,
+		Namespace:  ot.Namespace,
+		Name:       ot.Name,
+		UID:        ot.UID,
+	}
+}
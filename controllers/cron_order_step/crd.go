@@ -0,0 +1,69 @@
+package cron_order_step
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+)
+
+// CronOrderStepCustomResourceDefinition builds the v1 CRD of CronOrderStep, its schema is derived from the v1alpha1 types.
+func CronOrderStepCustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: v1alpha1.CronOrderStepCRDName,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: v1alpha1.OrderTaskGroup,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    v1alpha1.OrderTaskVersion,
+					Storage: true,
+					Served:  true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: k8s_utils.StructuralSchemaOf(reflect.TypeOf(v1alpha1.CronOrderStep{})),
+					},
+					Subresources: &apiextensionsv1.CustomResourceSubresources{
+						Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					},
+					AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+						{
+							Name:     "Schedule",
+							Type:     "string",
+							JSONPath: ".spec.schedule",
+						},
+						{
+							Name:     "Suspend",
+							Type:     "boolean",
+							JSONPath: ".spec.suspend",
+						},
+						{
+							Name:     "Last Schedule",
+							Type:     "date",
+							JSONPath: ".status.lastScheduleTime",
+						},
+						{
+							Name:     "Age",
+							Type:     "date",
+							JSONPath: ".metadata.creationTimestamp",
+						},
+					},
+				},
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     v1alpha1.CronOrderStepResourcePlural,
+				Singular:   "cronorderstep",
+				Kind:       reflect.TypeOf(v1alpha1.CronOrderStep{}).Name(),
+				ListKind:   reflect.TypeOf(v1alpha1.CronOrderStepList{}).Name(),
+				ShortNames: []string{"cor"},
+				Categories: []string{"all"},
+			},
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package cron_order_step
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+// maxMissedSchedules bounds how many missed times are walked through one by one, like the CronJob controller.
+const maxMissedSchedules = 100
+
+// ParseSchedule returns the schedule of the CronOrderStep read in its time zone.
+func ParseSchedule(cos *v1alpha1.CronOrderStep) (cron.Schedule, *time.Location, error) {
+	location := time.Local
+	if tz := cos.Spec.TimeZone; tz != nil && len(*tz) != 0 {
+		var err error
+		if location, err = time.LoadLocation(*tz); err != nil {
+			return nil, nil, fmt.Errorf("unknown timeZone %q: %w", *tz, err)
+		}
+	}
+	schedule, err := cron.ParseStandard(cos.Spec.Schedule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unparseable schedule %q: %w", cos.Spec.Schedule, err)
+	}
+	return schedule, location, nil
+}
+
+// mostRecentScheduleTime returns the latest time the CronOrderStep was scheduled at up to now which
+// has not been run yet, nil when there is none, and the time it is scheduled at next. The times
+// missed by more than the startingDeadlineSeconds are not looked at. tooMany is true when more than
+// maxMissedSchedules times were missed, the ones in between are then skipped. next is zero when
+// the schedule never fires, e.g. on the 30th of February.
+func mostRecentScheduleTime(cos *v1alpha1.CronOrderStep, schedule cron.Schedule, location *time.Location, now time.Time) (mostRecent *time.Time, next time.Time, tooMany bool) {
+	earliest := cos.CreationTimestamp.Time
+	if cos.Status.LastScheduleTime != nil {
+		earliest = cos.Status.LastScheduleTime.Time
+	}
+	if deadline := cos.Spec.StartingDeadlineSeconds; deadline != nil {
+		if start := now.Add(-time.Duration(*deadline) * time.Second); start.After(earliest) {
+			earliest = start
+		}
+	}
+
+	next = schedule.Next(earliest.In(location))
+	for missed := 0; !next.IsZero() && !next.After(now); missed++ {
+		if missed == maxMissedSchedules {
+			tooMany = true
+			next = schedule.Next(lastTimeBefore(schedule, now.In(location)))
+		}
+		t := next
+		mostRecent = &t
+		next = schedule.Next(next)
+	}
+	return mostRecent, next, tooMany
+}
+
+// lastTimeBefore returns a time from which the schedule next fires at or before now, it looks back
+// twice as far every time so that the missed times do not have to be walked through.
+func lastTimeBefore(schedule cron.Schedule, now time.Time) time.Time {
+	for back := time.Minute; ; back *= 2 {
+		from := now.Add(-back)
+		if !schedule.Next(from).After(now) {
+			return from
+		}
+	}
+}
+
+// OrderStepName is unique to the scheduled time so a run is never created twice.
+func OrderStepName(cos *v1alpha1.CronOrderStep, scheduledTime time.Time) string {
+	return fmt.Sprintf("%s-%d", cos.Name, scheduledTime.Unix()/60)
+}
@@ -0,0 +1,135 @@
+package cron_order_step
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"testing"
+	"time"
+)
+
+func TestMostRecentScheduleTime(t *testing.T) {
+	created := time.Date(2023, 1, 2, 0, 30, 0, 0, time.UTC)
+	at := func(d, h, m int) time.Time { return time.Date(2023, 1, d, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name           string
+		schedule       string
+		lastSchedule   *time.Time
+		deadline       *int64
+		now            time.Time
+		wantMostRecent *time.Time
+		wantNext       time.Time
+		wantTooMany    bool
+	}{
+		{
+			name:     "not due yet",
+			schedule: "0 2 * * *",
+			now:      at(2, 1, 0),
+			wantNext: at(2, 2, 0),
+		},
+		{
+			name:           "due",
+			schedule:       "0 2 * * *",
+			now:            at(2, 2, 0),
+			wantMostRecent: ptrTime(at(2, 2, 0)),
+			wantNext:       at(3, 2, 0),
+		},
+		{
+			name:         "already run",
+			schedule:     "0 2 * * *",
+			lastSchedule: ptrTime(at(2, 2, 0)),
+			now:          at(2, 3, 0),
+			wantNext:     at(3, 2, 0),
+		},
+		{
+			name:           "several missed",
+			schedule:       "0 * * * *",
+			now:            at(2, 5, 30),
+			wantMostRecent: ptrTime(at(2, 5, 0)),
+			wantNext:       at(2, 6, 0),
+		},
+		{
+			name:     "missed by more than the deadline",
+			schedule: "0 2 * * *",
+			deadline: pointer.Int64(60),
+			now:      at(2, 2, 5),
+			wantNext: at(3, 2, 0),
+		},
+		{
+			name:           "too many missed",
+			schedule:       "* * * * *",
+			now:            at(4, 0, 0),
+			wantMostRecent: ptrTime(at(4, 0, 0)),
+			wantNext:       at(4, 0, 1),
+			wantTooMany:    true,
+		},
+		{
+			// the zero next time used to be walked through forever
+			name:     "never fires",
+			schedule: "0 0 30 2 *",
+			now:      at(4, 0, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cos := &v1alpha1.CronOrderStep{
+				ObjectMeta: metav1.ObjectMeta{Name: "nightly", CreationTimestamp: metav1.NewTime(created)},
+				Spec:       v1alpha1.CronOrderStepSpec{Schedule: tt.schedule, TimeZone: pointer.String("UTC"), StartingDeadlineSeconds: tt.deadline},
+			}
+			if tt.lastSchedule != nil {
+				cos.Status.LastScheduleTime = &metav1.Time{Time: *tt.lastSchedule}
+			}
+			schedule, location, err := ParseSchedule(cos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mostRecent, next, tooMany := mostRecentScheduleTime(cos, schedule, location, tt.now)
+			if (mostRecent == nil) != (tt.wantMostRecent == nil) || mostRecent != nil && !mostRecent.Equal(*tt.wantMostRecent) {
+				t.Errorf("expected the most recent time %v, got %v", tt.wantMostRecent, mostRecent)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("expected the next time %v, got %v", tt.wantNext, next)
+			}
+			if tooMany != tt.wantTooMany {
+				t.Errorf("expected too many missed times: %t, got %t", tt.wantTooMany, tooMany)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		timeZone *string
+		wantErr  bool
+	}{
+		{name: "local", schedule: "0 2 * * *"},
+		{name: "time zone", schedule: "0 2 * * *", timeZone: pointer.String("Asia/Shanghai")},
+		{name: "unknown time zone", schedule: "0 2 * * *", timeZone: pointer.String("Mars/Olympus"), wantErr: true},
+		{name: "invalid schedule", schedule: "every day", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cos := &v1alpha1.CronOrderStep{Spec: v1alpha1.CronOrderStepSpec{Schedule: tt.schedule, TimeZone: tt.timeZone}}
+			if _, _, err := ParseSchedule(cos); (err != nil) != tt.wantErr {
+				t.Errorf("expected an error: %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOrderStepName(t *testing.T) {
+	cos := &v1alpha1.CronOrderStep{ObjectMeta: metav1.ObjectMeta{Name: "nightly"}}
+	scheduled := time.Date(2023, 1, 2, 2, 0, 0, 0, time.UTC)
+	if got, want := OrderStepName(cos, scheduled), "nightly-27877080"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if OrderStepName(cos, scheduled.Add(30*time.Second)) != OrderStepName(cos, scheduled) {
+		t.Error("expected the times of the same minute to share their name")
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,276 @@
+package order_step_run
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/lru"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
+	"time"
+)
+
+const (
+	defaultImageSize       = 100
+	defaultRunHistoryLimit = 10
+)
+
+// OrderStepRunController executes the OrderStep of every OrderStepRun with pods of the run's own
+// and cleans up the finished runs of an OrderStep past its runHistoryLimit.
+type OrderStepRunController struct {
+	manager       manager.Manager
+	kubeCli       kubernetes.Interface
+	eventRecorder record.EventRecorder
+	imageCache    *lru.Cache
+	gcOptions     pod_manager.GCOptions
+}
+
+func NewReconciler(mgr manager.Manager, apiextCli *apiextensionsclient.Clientset, gcOptions pod_manager.GCOptions) (*OrderStepRunController, error) {
+	kubeCli, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	reconciler := &OrderStepRunController{
+		manager:       mgr,
+		kubeCli:       kubeCli,
+		eventRecorder: mgr.GetEventRecorderFor(v1alpha1.OrderStepRunResourceKind),
+		imageCache:    lru.New(defaultImageSize),
+		gcOptions:     gcOptions,
+	}
+	return reconciler, k8s_utils.CreateCustomResourceDefinition(context.Background(), apiextCli,
+		OrderStepRunCustomResourceDefinition(), mgr.GetLogger())
+}
+
+func (c *OrderStepRunController) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	run := &v1alpha1.OrderStepRun{}
+	cli := c.manager.GetClient()
+	if err := cli.Get(ctx, req.NamespacedName, run); err != nil {
+		if k8s_utils.IsKubernetesResourceNotExist(err) {
+			// the child pod is garbage collected through its owner reference
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if run.Status.ResolvedSpec == nil {
+		if err := c.start(ctx, run); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	spec, err := pod_manager.ResolveRunSpec(ctx, c.manager.GetAPIReader(), run)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	podManager := pod_manager.NewRunPodManager(pod_manager.RunOrderStep(run, spec), cli, c.imageCache)
+	// the status is final once the ttl expired, the pods are being deleted
+	if left, _, ok := podManager.TTLExpiresIn(c.gcOptions, &run.Status, time.Now()); !ok || left > 0 {
+		if err = podManager.Builder(ctx); err != nil {
+			return reconcile.Result{}, err
+		}
+		if err = c.updateStatus(ctx, run, podManager); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	if run.Status.CompletionTime == nil {
+		return reconcile.Result{}, nil
+	}
+	result, err := c.collectGarbage(ctx, run, podManager)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return result, c.cleanupHistory(ctx, run)
+}
+
+// start snapshots the spec of the OrderStep into the status of the run before anything runs. The run
+// is labelled with its OrderStep and owned by it, deleting the OrderStep deletes its history.
+func (c *OrderStepRunController) start(ctx context.Context, run *v1alpha1.OrderStepRun) error {
+	cli := c.manager.GetClient()
+	spec, err := pod_manager.ResolveRunSpec(ctx, c.manager.GetAPIReader(), run)
+	if err != nil {
+		c.eventRecorder.Eventf(run, corev1.EventTypeWarning, "ResolutionFailed",
+			"failed to resolve OrderStep %s: %s", run.Spec.OrderStepRef.Name, err)
+		return err
+	}
+	ot := &v1alpha1.OrderStep{}
+	if err = cli.Get(ctx, types.NamespacedName{Namespace: run.Namespace, Name: run.Spec.OrderStepRef.Name}, ot); err != nil {
+		return err
+	}
+
+	if run.Labels == nil {
+		run.Labels = make(map[string]string)
+	}
+	run.Labels[v1alpha1.OrderStepLabel] = ot.Name
+	// the PodManager reads the annotations from the run, the ones of the OrderStep apply unless overridden
+	if value, ok := ot.Annotations[v1alpha1.SequentialResourcesAnnotation]; ok {
+		if run.Annotations == nil {
+			run.Annotations = make(map[string]string)
+		}
+		if _, overridden := run.Annotations[v1alpha1.SequentialResourcesAnnotation]; !overridden {
+			run.Annotations[v1alpha1.SequentialResourcesAnnotation] = value
+		}
+	}
+	if err = controllerutil.SetOwnerReference(ot, run, c.manager.GetScheme()); err != nil {
+		return fmt.Errorf("failed to set the owner of OrderStepRun %s: %w", run.Name, err)
+	}
+	if err = cli.Update(ctx, run); err != nil {
+		return err
+	}
+
+	// the status and approvals are always read from the run itself
+	spec.Status = ""
+	spec.Approvals = nil
+	run.Status.ResolvedSpec = spec
+	return cli.Status().Update(ctx, run)
+}
+
+// updateStatus writes the status computed from the child pod, or the step pods, back to the run.
+func (c *OrderStepRunController) updateStatus(ctx context.Context, run *v1alpha1.OrderStepRun, pm *pod_manager.PodManager) error {
+	pod, err := pm.GetTaskPod(ctx)
+	if err != nil {
+		if !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return err
+		}
+		pod = nil
+	}
+	// the pod, or the Job, may be cleaned up once the run finished, its last status is kept
+	if pod == nil && run.Status.CompletionTime != nil {
+		return nil
+	}
+
+	status := pm.ComputeStatus(pod)
+	if reflect.DeepEqual(run.Status, status) {
+		return nil
+	}
+
+	if status.Phase != run.Status.Phase {
+		eventType := corev1.EventTypeNormal
+		if status.Phase == v1alpha1.OrderStepFailed {
+			eventType = corev1.EventTypeWarning
+		}
+		reason := string(status.Phase)
+		message := fmt.Sprintf("OrderStepRun %s/%s is %s", run.Namespace, run.Name, status.Phase)
+		if cond := meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionSucceeded); cond != nil && len(cond.Message) != 0 {
+			reason = cond.Reason
+			message = fmt.Sprintf("%s: %s", message, cond.Message)
+		}
+		c.eventRecorder.Event(run, eventType, reason, message)
+	}
+
+	run.Status = status
+	return c.manager.GetClient().Status().Update(ctx, run)
+}
+
+// collectGarbage deletes the pods of the finished run once its ttl expired, the tail of the step logs
+// is kept in the status. With deleteAfterTTL the run itself is deleted instead.
+func (c *OrderStepRunController) collectGarbage(ctx context.Context, run *v1alpha1.OrderStepRun, pm *pod_manager.PodManager) (reconcile.Result, error) {
+	left, deleteTask, ok := pm.TTLExpiresIn(c.gcOptions, &run.Status, time.Now())
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+	if left > 0 {
+		return reconcile.Result{RequeueAfter: left}, nil
+	}
+
+	cli := c.manager.GetClient()
+	if deleteTask {
+		err := cli.Delete(ctx, run, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	status := run.Status.DeepCopy()
+	if err := pm.RecordLogs(ctx, c.kubeCli, status); err != nil {
+		return reconcile.Result{}, err
+	}
+	if !reflect.DeepEqual(run.Status, *status) {
+		run.Status = *status
+		if err := cli.Status().Update(ctx, run); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	deleted, err := pm.DeleteTaskPods(ctx)
+	if deleted {
+		c.eventRecorder.Event(run, corev1.EventTypeNormal, "PodsDeleted",
+			"deleted the pods of the finished OrderStepRun, ttlSecondsAfterFinished expired")
+	}
+	return reconcile.Result{}, err
+}
+
+// cleanupHistory deletes the oldest finished runs of the OrderStep beyond its runHistoryLimit.
+func (c *OrderStepRunController) cleanupHistory(ctx context.Context, run *v1alpha1.OrderStepRun) error {
+	cli := c.manager.GetClient()
+	ot := &v1alpha1.OrderStep{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: run.Namespace, Name: run.Spec.OrderStepRef.Name}, ot); err != nil {
+		if k8s_utils.IsKubernetesResourceNotExist(err) {
+			// the runs are garbage collected along with the OrderStep
+			return nil
+		}
+		return err
+	}
+	keep := defaultRunHistoryLimit
+	if ot.Spec.RunHistoryLimit != nil {
+		keep = int(*ot.Spec.RunHistoryLimit)
+	}
+
+	list := &v1alpha1.OrderStepRunList{}
+	err := cli.List(ctx, list, client.InNamespace(run.Namespace), client.MatchingLabels{v1alpha1.OrderStepLabel: ot.Name})
+	if err != nil {
+		return err
+	}
+	var finished []*v1alpha1.OrderStepRun
+	for i := range list.Items {
+		if list.Items[i].Status.CompletionTime != nil {
+			finished = append(finished, &list.Items[i])
+		}
+	}
+	if len(finished) <= keep {
+		return nil
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreationTimestamp.Before(&finished[j].CreationTimestamp)
+	})
+	for _, old := range finished[:len(finished)-keep] {
+		err = cli.Delete(ctx, old, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *OrderStepRunController) OnUpdateFunc(_ context.Context, event event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+	// the pods of a Job are owned by the Job, not by the run
+	if name, ok := event.ObjectNew.GetAnnotations()[v1alpha1.OrderStepRunNameAnnotation]; ok {
+		limitingInterface.Add(reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name: name, Namespace: event.ObjectNew.GetNamespace(),
+			},
+		})
+	}
+	for _, ref := range event.ObjectNew.GetOwnerReferences() {
+		if ref.Kind == v1alpha1.OrderStepRunResourceKind && ref.APIVersion == v1alpha1.OrderTaskApiVersionGroup {
+			limitingInterface.Add(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: ref.Name, Namespace: event.ObjectNew.GetNamespace(),
+				},
+			})
+		}
+	}
+}
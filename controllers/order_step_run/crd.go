@@ -0,0 +1,69 @@
+package order_step_run
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+)
+
+// OrderStepRunCustomResourceDefinition builds the v1 CRD of OrderStepRun, its schema is derived from the v1alpha1 types.
+func OrderStepRunCustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: v1alpha1.OrderStepRunCRDName,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: v1alpha1.OrderTaskGroup,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    v1alpha1.OrderTaskVersion,
+					Storage: true,
+					Served:  true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: k8s_utils.StructuralSchemaOf(reflect.TypeOf(v1alpha1.OrderStepRun{})),
+					},
+					Subresources: &apiextensionsv1.CustomResourceSubresources{
+						Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					},
+					AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+						{
+							Name:     "OrderStep",
+							Type:     "string",
+							JSONPath: ".spec.orderStepRef.name",
+						},
+						{
+							Name:     "Phase",
+							Type:     "string",
+							JSONPath: ".status.phase",
+						},
+						{
+							Name:     "Current Step",
+							Type:     "string",
+							JSONPath: ".status.currentStep",
+						},
+						{
+							Name:     "Age",
+							Type:     "date",
+							JSONPath: ".metadata.creationTimestamp",
+						},
+					},
+				},
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     v1alpha1.OrderStepRunResourcePlural,
+				Singular:   "ordersteprun",
+				Kind:       reflect.TypeOf(v1alpha1.OrderStepRun{}).Name(),
+				ListKind:   reflect.TypeOf(v1alpha1.OrderStepRunList{}).Name(),
+				ShortNames: []string{"osr"},
+				Categories: []string{"all"},
+			},
+		},
+	}
+}
@@ -0,0 +1,112 @@
+package order_step_run
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"testing"
+	"time"
+)
+
+// clientManager is a manager only handing out its client.
+type clientManager struct {
+	manager.Manager
+	client client.Client
+}
+
+func (m *clientManager) GetClient() client.Client {
+	return m.client
+}
+
+func TestCollectGarbage(t *testing.T) {
+	tests := []struct {
+		name           string
+		ttl            int32
+		deleteAfterTTL bool
+		wantRequeue    bool
+		wantRun        bool
+		wantPod        bool
+		wantLogs       string
+	}{
+		{name: "not expired", ttl: 3600, wantRequeue: true, wantRun: true, wantPod: true},
+		{name: "pods deleted", wantRun: true, wantLogs: "fake logs"},
+		// the pods go along with the run through their owner reference
+		{name: "run deleted", deleteAfterTTL: true, wantPod: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			step := v1alpha1.Step{Container: corev1.Container{Name: "compile", Image: "alpine:3.18", Command: []string{"true"}}}
+			// the ttl is read from the spec the run resolved
+			run := &v1alpha1.OrderStepRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "build-1", Namespace: "default"},
+				Spec:       v1alpha1.OrderStepRunSpec{OrderStepRef: v1alpha1.OrderStepRef{Name: "build"}},
+				Status: v1alpha1.OrderStepStatus{
+					CompletionTime: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+					Steps:          []v1alpha1.StepStatus{{Name: "compile", Container: "compile"}},
+					ResolvedSpec: &v1alpha1.OrderStepSpec{
+						Steps:                   []v1alpha1.Step{step},
+						RunPolicy:               v1alpha1.RunPolicyManual,
+						TTLSecondsAfterFinished: pointer.Int32(tt.ttl),
+						DeleteAfterTTL:          pointer.Bool(tt.deleteAfterTTL),
+					},
+				},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: pod_manager.GenerateRunBaseName("build-1"), Namespace: "default"},
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded, ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "compile",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+				}}},
+			}
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(run, pod).WithStatusSubresource(run).Build()
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(run), run); err != nil {
+				t.Fatal(err)
+			}
+			c := &OrderStepRunController{
+				manager:       &clientManager{client: cli},
+				kubeCli:       kubefake.NewSimpleClientset(),
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+
+			pm := pod_manager.NewRunPodManager(pod_manager.RunOrderStep(run, run.Status.ResolvedSpec), cli, nil)
+			result, err := c.collectGarbage(ctx, run, pm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != tt.wantRequeue {
+				t.Errorf("expected a requeue %t, got %s", tt.wantRequeue, result.RequeueAfter)
+			}
+			err = cli.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})
+			if exists := !apierrors.IsNotFound(err); exists != tt.wantPod {
+				t.Errorf("expected the pod to exist %t, got %v", tt.wantPod, err)
+			}
+			got := &v1alpha1.OrderStepRun{}
+			err = cli.Get(ctx, client.ObjectKeyFromObject(run), got)
+			if exists := !apierrors.IsNotFound(err); exists != tt.wantRun {
+				t.Fatalf("expected the run to exist %t, got %v", tt.wantRun, err)
+			}
+			if tt.wantRun && got.Status.Steps[0].Logs != tt.wantLogs {
+				t.Errorf("expected the logs %q to be kept, got %q", tt.wantLogs, got.Status.Steps[0].Logs)
+			}
+		})
+	}
+}
@@ -4,20 +4,15 @@ import (
 	"context"
 	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
 	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
-	"github.com/daicheng123/ordertask-operator/pkg/k8s/clientset/versioned"
 	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
 	"github.com/daicheng123/ordertask-operator/pkg/utils/list"
-	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	k8sErr "k8s.io/apimachinery/pkg/util/errors"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/lru"
-	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -36,18 +31,27 @@ type OrderTaskReconciler interface {
 }
 
 type OrderTaskController struct {
-	crdCli        *versioned.Clientset
+	kubeCli       kubernetes.Interface
 	manager       manager.Manager
 	eventRecorder record.EventRecorder
 	imageCache    *lru.Cache
 	eventQueue    *list.SafeListLimited
 	errorChan     chan error
+
+	// gcOptions are the defaults of the garbage collection of the pods of finished OrderSteps
+	gcOptions pod_manager.GCOptions
 }
 
-func NewReconciler(mgr manager.Manager, crdCli *versioned.Clientset, apiextCli *apiextensionsclient.Clientset) (OrderTaskReconciler, error) {
+func NewReconciler(mgr manager.Manager, apiextCli *apiextensionsclient.Clientset, gcOptions pod_manager.GCOptions) (OrderTaskReconciler, error) {
+	// the logs of the steps are only read through a clientset
+	kubeCli, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
 	reconciler := &OrderTaskController{
 		manager:       mgr,
-		crdCli:        crdCli,
+		kubeCli:       kubeCli,
+		gcOptions:     gcOptions,
 		eventRecorder: mgr.GetEventRecorderFor(v1alpha1.OrderTaskResourceKind),
 		imageCache: lru.NewWithEvictionFunc(defaultImageSize, func(key lru.Key, value interface{}) {
 
@@ -65,81 +69,61 @@ func (otc *OrderTaskController) Reconcile(ctx context.Context, req reconcile.Req
 	ot := &v1alpha1.OrderStep{}
 	client := otc.manager.GetClient()
 	err := client.Get(ctx, req.NamespacedName, ot)
+	if err != nil {
+		if k8s_utils.IsKubernetesResourceNotExist(err) {
+			// the child pod is garbage collected through its owner reference
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
 
-	if err == nil || (err != nil && k8s_utils.IsKubernetesResourceNotExist(err)) {
-
-		podManager := pod_manager.NewPodManager(ot, client, otc.imageCache)
+	// a definition is only executed by its OrderStepRuns
+	if ot.Spec.RunPolicy == v1alpha1.RunPolicyManual {
+		return reconcile.Result{}, nil
+	}
+	if err = otc.resolveSpec(ctx, ot); err != nil {
+		return reconcile.Result{}, err
+	}
+	podManager := pod_manager.NewPodManager(ot, client, otc.imageCache)
+	// the status is final once the ttl expired, the pods are being deleted
+	if left, _, ok := podManager.TTLExpiresIn(otc.gcOptions, &ot.Status, time.Now()); !ok || left > 0 {
 		if err = podManager.Builder(ctx); err != nil {
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, nil
+		if err = otc.updateStatus(ctx, ot, podManager); err != nil {
+			return reconcile.Result{}, err
+		}
 	}
-	k8s_utils.TaskAddNormalEvent[&v1alpha1.OrderStep{}](otc.eventRecorder, ot.GetNamespace()+"/"+ot.GetName())
-	// otc.eventRecorder.Eventf(&v1alpha1.OrderStep{}, apicoreV1.EventTypeNormal, "start", "")
-	return reconcile.Result{}, err
+	return otc.collectGarbage(ctx, ot, podManager)
 }
 
 func (otc *OrderTaskController) createCustomResourceDefinition(ctx context.Context, apiextCli *apiextensionsclient.Clientset) error {
-	crd := &apiextensionsv1beta1.CustomResourceDefinition{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: v1alpha1.OrderTaskCRDName,
-		},
-		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
-			Group: api.SchemeGroupVersion.Group,
-			Versions: []apiextensionsv1beta1.CustomResourceDefinitionVersion{
-				{
-					Name:    v1alpha1.OrderTaskVersion,
-					Storage: true,
-					Served:  true,
-				},
-			},
-			Scope: apiextensionsv1beta1.NamespaceScoped,
-			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
-				Plural: v1alpha1.OrderTaskResourcePlural,
-				Kind:   reflect.TypeOf(v1alpha1.OrderStep{}).Name(),
-			},
-		},
-	}
-	_, err := apiextCli.ApiextensionsV1beta1().CustomResourceDefinitions().Create(ctx, crd, metav1.CreateOptions{})
-	if err != nil && !k8s_utils.IsKubernetesResourceAlreadyExistError(err) {
-		return err
+	crds := []*apiextensionsv1.CustomResourceDefinition{
+		OrderStepCustomResourceDefinition(),
+		OrderStepTemplateCustomResourceDefinition(),
+		ClusterOrderStepTemplateCustomResourceDefinition(),
 	}
-	// wait for order task crd resource being created
-	otc.manager.GetLogger().Info("creating crd resource, wating till its established")
-	err = wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, 60*time.Second, false, func(ctx context.Context) (done bool, err error) {
-		crd, err = apiextCli.ApiextensionsV1beta1().CustomResourceDefinitions().Get(ctx, v1alpha1.OrderTaskCRDName, metav1.GetOptions{})
-		if err != nil {
-			return false, err
+	for _, crd := range crds {
+		if err := k8s_utils.CreateCustomResourceDefinition(ctx, apiextCli, crd, otc.manager.GetLogger()); err != nil {
+			return err
 		}
-		for _, cond := range crd.Status.Conditions {
-			switch cond.Type {
-			case apiextensionsv1beta1.Established:
-				if cond.Status == apiextensionsv1beta1.ConditionTrue {
-					return true, err
-				}
-			case apiextensionsv1beta1.NamesAccepted:
-				if cond.Status == apiextensionsv1beta1.ConditionFalse {
-					//otc.logger.WithName().
-				}
-			}
-		}
-		return false, err
-	})
-	if err != nil {
-		deleteErr := apiextCli.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(ctx, v1alpha1.OrderTaskCRDName, metav1.DeleteOptions{})
-		if deleteErr != nil {
-			return k8sErr.NewAggregate([]error{err, deleteErr})
-		}
-		return err
 	}
 	return nil
 }
 
 func (otc *OrderTaskController) OnUpdateFunc(_ context.Context, event event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+	// the pods of a Job are owned by the Job, not by the OrderStep
+	if name, ok := event.ObjectNew.GetAnnotations()[v1alpha1.OrderStepNameAnnotation]; ok {
+		limitingInterface.Add(reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name: name, Namespace: event.ObjectNew.GetNamespace(),
+			},
+		})
+	}
 	for _, ref := range event.ObjectNew.GetOwnerReferences() {
 		if ref.Kind == v1alpha1.OrderTaskResourceKind && ref.APIVersion == v1alpha1.OrderTaskApiVersionGroup {
 			limitingInterface.Add(reconcile.Request{
-				types.NamespacedName{
+				NamespacedName: types.NamespacedName{
 					Name: ref.Name, Namespace: event.ObjectNew.GetNamespace(),
 				},
 			})
@@ -0,0 +1,118 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"strings"
+)
+
+// OrderStepCustomResourceDefinition builds the v1 CRD of OrderStep, its schema is derived from the v1alpha1 types.
+func OrderStepCustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: v1alpha1.OrderTaskCRDName,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: v1alpha1.OrderTaskGroup,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    v1alpha1.OrderTaskVersion,
+					Storage: true,
+					Served:  true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: k8s_utils.StructuralSchemaOf(reflect.TypeOf(v1alpha1.OrderStep{})),
+					},
+					Subresources: &apiextensionsv1.CustomResourceSubresources{
+						Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					},
+					AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+						{
+							Name:     "Phase",
+							Type:     "string",
+							JSONPath: ".status.phase",
+						},
+						{
+							Name:     "Current Step",
+							Type:     "string",
+							JSONPath: ".status.currentStep",
+						},
+						{
+							Name:     "Age",
+							Type:     "date",
+							JSONPath: ".metadata.creationTimestamp",
+						},
+					},
+				},
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     v1alpha1.OrderTaskResourcePlural,
+				Singular:   "orderstep",
+				Kind:       reflect.TypeOf(v1alpha1.OrderStep{}).Name(),
+				ListKind:   reflect.TypeOf(v1alpha1.OrderStepList{}).Name(),
+				ShortNames: []string{"or"},
+				Categories: []string{"all"},
+			},
+		},
+	}
+}
+
+// OrderStepTemplateCustomResourceDefinition builds the v1 CRD of the namespaced OrderStepTemplate.
+func OrderStepTemplateCustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	return templateCustomResourceDefinition(v1alpha1.OrderStepTemplateCRDName, reflect.TypeOf(v1alpha1.OrderStepTemplate{}),
+		reflect.TypeOf(v1alpha1.OrderStepTemplateList{}), v1alpha1.OrderStepTemplateResourcePlural, apiextensionsv1.NamespaceScoped, "ost")
+}
+
+// ClusterOrderStepTemplateCustomResourceDefinition builds the v1 CRD of the cluster scoped ClusterOrderStepTemplate.
+func ClusterOrderStepTemplateCustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	return templateCustomResourceDefinition(v1alpha1.ClusterOrderStepTemplateCRDName, reflect.TypeOf(v1alpha1.ClusterOrderStepTemplate{}),
+		reflect.TypeOf(v1alpha1.ClusterOrderStepTemplateList{}), v1alpha1.ClusterOrderStepTemplateResourcePlural, apiextensionsv1.ClusterScoped, "cost")
+}
+
+func templateCustomResourceDefinition(name string, kind, listKind reflect.Type, plural string,
+	scope apiextensionsv1.ResourceScope, shortName string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: v1alpha1.OrderTaskGroup,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    v1alpha1.OrderTaskVersion,
+					Storage: true,
+					Served:  true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: k8s_utils.StructuralSchemaOf(kind),
+					},
+					AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+						{
+							Name:     "Age",
+							Type:     "date",
+							JSONPath: ".metadata.creationTimestamp",
+						},
+					},
+				},
+			},
+			Scope: scope,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     plural,
+				Singular:   strings.ToLower(kind.Name()),
+				Kind:       kind.Name(),
+				ListKind:   listKind.Name(),
+				ShortNames: []string{shortName},
+			},
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+// collectGarbage deletes the pods of the finished OrderStep once its ttl expired, the tail of the step logs
+// is kept in the status. With deleteAfterTTL the OrderStep itself is deleted instead.
+func (otc *OrderTaskController) collectGarbage(ctx context.Context, ot *v1alpha1.OrderStep, pm *pod_manager.PodManager) (reconcile.Result, error) {
+	left, deleteTask, ok := pm.TTLExpiresIn(otc.gcOptions, &ot.Status, time.Now())
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+	if left > 0 {
+		return reconcile.Result{RequeueAfter: left}, nil
+	}
+
+	cli := otc.manager.GetClient()
+	if deleteTask {
+		// the pods are garbage collected through their owner reference
+		err := cli.Delete(ctx, ot, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	status := ot.Status.DeepCopy()
+	if err := pm.RecordLogs(ctx, otc.kubeCli, status); err != nil {
+		return reconcile.Result{}, err
+	}
+	if !reflect.DeepEqual(ot.Status, *status) {
+		ot.Status = *status
+		if err := cli.Status().Update(ctx, ot); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	deleted, err := pm.DeleteTaskPods(ctx)
+	if deleted {
+		otc.eventRecorder.Event(ot, corev1.EventTypeNormal, "PodsDeleted",
+			"deleted the pods of the finished OrderStep, ttlSecondsAfterFinished expired")
+	}
+	return reconcile.Result{}, err
+}
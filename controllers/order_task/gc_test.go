@@ -0,0 +1,108 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"testing"
+	"time"
+)
+
+// clientManager is a manager only handing out its client.
+type clientManager struct {
+	manager.Manager
+	client client.Client
+}
+
+func (m *clientManager) GetClient() client.Client {
+	return m.client
+}
+
+func TestCollectGarbage(t *testing.T) {
+	tests := []struct {
+		name           string
+		ttl            int32
+		deleteAfterTTL bool
+		wantRequeue    bool
+		wantTask       bool
+		wantPod        bool
+		wantLogs       string
+	}{
+		{name: "not expired", ttl: 3600, wantRequeue: true, wantTask: true, wantPod: true},
+		{name: "pods deleted", wantTask: true, wantLogs: "fake logs"},
+		// the pods go along with the OrderStep through their owner reference
+		{name: "OrderStep deleted", deleteAfterTTL: true, wantPod: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			step := v1alpha1.Step{Container: corev1.Container{Name: "compile", Image: "alpine:3.18", Command: []string{"true"}}}
+			ot := &v1alpha1.OrderStep{
+				ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "default"},
+				Spec: v1alpha1.OrderStepSpec{
+					Steps:                   []v1alpha1.Step{step},
+					TTLSecondsAfterFinished: pointer.Int32(tt.ttl),
+					DeleteAfterTTL:          pointer.Bool(tt.deleteAfterTTL),
+				},
+				Status: v1alpha1.OrderStepStatus{
+					CompletionTime: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+					Steps:          []v1alpha1.StepStatus{{Name: "compile", Container: "compile"}},
+				},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: pod_manager.GenerateBaseName("build"), Namespace: "default"},
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded, ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "compile",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+				}}},
+			}
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ot, pod).WithStatusSubresource(ot).Build()
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(ot), ot); err != nil {
+				t.Fatal(err)
+			}
+			otc := &OrderTaskController{
+				manager:       &clientManager{client: cli},
+				kubeCli:       kubefake.NewSimpleClientset(),
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+
+			result, err := otc.collectGarbage(ctx, ot, pod_manager.NewPodManager(ot, cli, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != tt.wantRequeue {
+				t.Errorf("expected a requeue %t, got %s", tt.wantRequeue, result.RequeueAfter)
+			}
+			err = cli.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})
+			if exists := !apierrors.IsNotFound(err); exists != tt.wantPod {
+				t.Errorf("expected the pod to exist %t, got %v", tt.wantPod, err)
+			}
+			got := &v1alpha1.OrderStep{}
+			err = cli.Get(ctx, client.ObjectKeyFromObject(ot), got)
+			if exists := !apierrors.IsNotFound(err); exists != tt.wantTask {
+				t.Fatalf("expected the OrderStep to exist %t, got %v", tt.wantTask, err)
+			}
+			if tt.wantTask && got.Status.Steps[0].Logs != tt.wantLogs {
+				t.Errorf("expected the logs %q to be kept, got %q", tt.wantLogs, got.Status.Steps[0].Logs)
+			}
+		})
+	}
+}
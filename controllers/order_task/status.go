@@ -0,0 +1,50 @@
+package order_task
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"reflect"
+)
+
+// updateStatus writes the status computed from the child pod, or the step pods, back to the OrderStep.
+func (otc *OrderTaskController) updateStatus(ctx context.Context, ot *v1alpha1.OrderStep, pm *pod_manager.PodManager) error {
+	pod, err := pm.GetTaskPod(ctx)
+	if err != nil {
+		if !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return err
+		}
+		pod = nil
+	}
+	// the pod, or the Job, may be cleaned up once the OrderStep finished, its last status is kept
+	if pod == nil && ot.Status.CompletionTime != nil {
+		return nil
+	}
+
+	status := pm.ComputeStatus(pod)
+	if reflect.DeepEqual(ot.Status, status) {
+		return nil
+	}
+
+	if status.Phase != ot.Status.Phase {
+		eventType := corev1.EventTypeNormal
+		if status.Phase == v1alpha1.OrderStepFailed {
+			eventType = corev1.EventTypeWarning
+		}
+		// the reason of the Succeeded condition tells a timeout apart from a plain failure
+		reason := string(status.Phase)
+		message := fmt.Sprintf("OrderTask %s/%s is %s", ot.Namespace, ot.Name, status.Phase)
+		if cond := meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionSucceeded); cond != nil && len(cond.Message) != 0 {
+			reason = cond.Reason
+			message = fmt.Sprintf("%s: %s", message, cond.Message)
+		}
+		otc.eventRecorder.Event(ot, eventType, reason, message)
+	}
+
+	ot.Status = status
+	return otc.manager.GetClient().Status().Update(ctx, ot)
+}
@@ -0,0 +1,31 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// resolveSpec replaces the spec of an OrderStep referencing a template with the resolved one the
+// PodManager builds from, the first resolution is snapshotted into the status before anything runs.
+func (otc *OrderTaskController) resolveSpec(ctx context.Context, ot *v1alpha1.OrderStep) error {
+	if ot.Spec.TemplateRef == nil {
+		return nil
+	}
+	spec, err := pod_manager.ResolveSpec(ctx, otc.manager.GetAPIReader(), ot)
+	if err != nil {
+		otc.eventRecorder.Eventf(ot, corev1.EventTypeWarning, "TemplateResolutionFailed",
+			"failed to resolve template %s: %s", ot.Spec.TemplateRef.Name, err)
+		return err
+	}
+	if ot.Status.ResolvedSpec == nil {
+		ot.Status.ResolvedSpec = spec.DeepCopy()
+		if err = otc.manager.GetClient().Status().Update(ctx, ot); err != nil {
+			return err
+		}
+	}
+	// the spec is only changed in memory, the status updates leave it alone
+	ot.Spec = *spec
+	return nil
+}
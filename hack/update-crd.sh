@@ -0,0 +1,5 @@
+#!/usr/bin/env bash
+set -e
+cd $(dirname $0)/..
+
+go run ./cmd/crdgen > examples/orderstep-operator/crds.yml
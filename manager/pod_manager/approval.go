@@ -0,0 +1,97 @@
+package pod_manager
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"strings"
+)
+
+// approval returns the approval signing off the step with the given name.
+func (pm *PodManager) approval(name string) (v1alpha1.Approval, bool) {
+	for _, approval := range pm.task.Spec.Approvals {
+		if approval.Step == name {
+			return approval, true
+		}
+	}
+	return v1alpha1.Approval{}, false
+}
+
+// held reports whether the order must not move onto the step at order, an approval step
+// waiting to be approved.
+func (pm *PodManager) held(order int) bool {
+	steps := pm.allSteps()
+	if order < 1 || order > len(steps) || steps[order-1].Approval == nil {
+		return false
+	}
+	_, ok := pm.approval(StepName(order-1, steps[order-1]))
+	return !ok
+}
+
+// awaitingApproval returns the indexes of the approval steps the order is held before, it
+// mirrors where forward and forwardDAG stop from the step statuses computed so far.
+func (pm *PodManager) awaitingApproval(state *podState, statuses []v1alpha1.StepStatus) []int {
+	steps := pm.allSteps()
+	for i, s := range statuses {
+		// the finally steps run next, the approval steps are passed over
+		failed := s.Reason == v1alpha1.StepReasonFailed || s.Reason == v1alpha1.StepReasonTimedOut
+		if failed && steps[i].OnError != v1alpha1.OnErrorContinue && i < len(pm.task.Spec.Steps) {
+			return nil
+		}
+	}
+	variables := func() map[string]string {
+		return pm.statusVariables(statuses)
+	}
+
+	if state.readySet == nil {
+		order := state.order
+		if order >= 1 {
+			if order > len(steps) || statuses[order-1].FinishedAt == nil {
+				return nil
+			}
+		} else if order < 0 {
+			return nil
+		}
+		next := pm.nextOrderWith(order+1, variables)
+		if !pm.held(next) || statuses[next-1].Reason != v1alpha1.StepReasonWaiting {
+			return nil
+		}
+		return []int{next - 1}
+	}
+
+	set := state.readySet
+	done := make([]bool, len(steps))
+	for i := range steps {
+		done[i] = set.skip[i+1] || set.run[i+1] && statuses[i].FinishedAt != nil
+	}
+	var vars map[string]string
+	var indexes []int
+	for i, dep := range pm.predecessors() {
+		order := i + 1
+		if set.run[order] || set.skip[order] || !pm.held(order) || !dep.satisfied(done) {
+			continue
+		}
+		if len(steps[i].When) != 0 {
+			if vars == nil {
+				vars = variables()
+			}
+			if !whenHolds(steps[i], vars) {
+				continue
+			}
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// approvalMessage tells which steps await approval, with the message of their gate.
+func approvalMessage(steps []v1alpha1.Step, statuses []v1alpha1.StepStatus, indexes []int) string {
+	messages := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		message := fmt.Sprintf("step %s awaits approval", statuses[i].Name)
+		if len(steps[i].Approval.Message) != 0 {
+			message = fmt.Sprintf("%s: %s", message, steps[i].Approval.Message)
+		}
+		messages = append(messages, message)
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,112 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestHeld(t *testing.T) {
+	tests := []struct {
+		name      string
+		order     int
+		approvals []v1alpha1.Approval
+		want      bool
+	}{
+		{name: "not an approval step", order: 1},
+		{name: "waiting", order: 2, want: true},
+		{name: "approved", order: 2, approvals: []v1alpha1.Approval{{Step: "release"}}},
+		{name: "approval of another step", order: 2, approvals: []v1alpha1.Approval{{Step: "build"}}, want: true},
+		{name: "past the last step", order: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(namedStep("build"), approvalGate("release"), namedStep("deploy"))
+			pm.task.Spec.Approvals = tt.approvals
+			if got := pm.held(tt.order); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAwaitingApproval(t *testing.T) {
+	approvedAt := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name      string
+		approvals []v1alpha1.Approval
+		// pod is at order 1, the step before the gate finished
+		states map[string]corev1.ContainerState
+
+		wantNext     string
+		wantOk       bool
+		wantReason   string
+		wantAwaiting bool
+		wantBy       string
+	}{
+		{
+			name:         "waiting",
+			states:       map[string]corev1.ContainerState{"build": exited(0, ""), "release": running()},
+			wantReason:   v1alpha1.StepReasonAwaitingApproval,
+			wantAwaiting: true,
+		},
+		{
+			name:         "approval of another step",
+			approvals:    []v1alpha1.Approval{{Step: "build", ApprovedBy: "alice", ApprovedAt: &approvedAt}},
+			states:       map[string]corev1.ContainerState{"build": exited(0, ""), "release": running()},
+			wantReason:   v1alpha1.StepReasonAwaitingApproval,
+			wantAwaiting: true,
+		},
+		{
+			name:       "approved",
+			approvals:  []v1alpha1.Approval{{Step: "release", ApprovedBy: "alice", ApprovedAt: &approvedAt}},
+			states:     map[string]corev1.ContainerState{"build": exited(0, ""), "release": running()},
+			wantNext:   "2",
+			wantOk:     true,
+			wantReason: v1alpha1.StepReasonWaiting,
+			wantBy:     "alice",
+		},
+		{
+			// the order never reaches the gate
+			name:       "step before failed",
+			states:     map[string]corev1.ContainerState{"build": exited(1, ""), "release": running()},
+			wantNext:   annotationTaskExistValue,
+			wantOk:     true,
+			wantReason: v1alpha1.StepReasonWaiting,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(namedStep("build"), approvalGate("release"), namedStep("deploy"))
+			pm.task.Spec.Approvals = tt.approvals
+			pod := taskPod(corev1.PodRunning, "1", tt.states)
+
+			next, ok := pm.followingOrder(1, pod)
+			if next != tt.wantNext || ok != tt.wantOk {
+				t.Errorf("expected the order %q/%t, got %q/%t", tt.wantNext, tt.wantOk, next, ok)
+			}
+
+			status := pm.ComputeStatus(pod)
+			release := status.Steps[1]
+			if release.Reason != tt.wantReason {
+				t.Errorf("expected the gate to be %s, got %s", tt.wantReason, release.Reason)
+			}
+			condition := meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionAwaitingApproval)
+			if awaiting := condition != nil && condition.Status == metav1.ConditionTrue; awaiting != tt.wantAwaiting {
+				t.Errorf("expected awaiting approval %t, got %+v", tt.wantAwaiting, condition)
+			}
+			if release.ApprovedBy != tt.wantBy {
+				t.Errorf("expected the gate approved by %q, got %q", tt.wantBy, release.ApprovedBy)
+			}
+			if approved := release.ApprovedAt != nil && release.ApprovedAt.Equal(&approvedAt); approved != (len(tt.wantBy) != 0) {
+				t.Errorf("expected the gate approved at %s, got %v", approvedAt, release.ApprovedAt)
+			}
+			if status.Steps[0].ApprovedBy != "" {
+				t.Error("expected only the approval steps to record their approval")
+			}
+		})
+	}
+}
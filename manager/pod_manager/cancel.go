@@ -0,0 +1,49 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+const (
+	// annotationTaskCancelledValue is added to the order annotation once the OrderStep is cancelled,
+	// the entrypoints terminate the running steps and skip the others as cancelled.
+	annotationTaskCancelledValue = "-2"
+)
+
+func (pm *PodManager) isCancelRequested() bool {
+	return pm.task.Spec.Status == v1alpha1.OrderStepSpecStatusCancelled
+}
+
+func (pm *PodManager) isPaused() bool {
+	return pm.task.Spec.Status == v1alpha1.OrderStepSpecStatusPaused
+}
+
+// cancel hands the cancellation to the entrypoints of the pod through its order annotation.
+func (pm *PodManager) cancel(ctx context.Context, pod *corev1.Pod) error {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || isTaskCancelled(pod) {
+		return nil
+	}
+	value := annotationTaskCancelledValue
+	// the steps of a graph still need to know whether they were released
+	if pm.isDAG() {
+		value = pod.Annotations[annotationsOrderField] + "," + annotationTaskCancelledValue
+	}
+	pod.Annotations[annotationsOrderField] = value
+	return pm.Client.Update(ctx, pod)
+}
+
+// isTaskCancelled reports whether the order annotation of the pod tells the task was cancelled.
+func isTaskCancelled(pod *corev1.Pod) bool {
+	if pod == nil {
+		return false
+	}
+	for _, token := range strings.Split(pod.Annotations[annotationsOrderField], ",") {
+		if token == annotationTaskCancelledValue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []v1alpha1.Step
+		phase corev1.PodPhase
+		value string
+		// want is the annotation written, empty when the pod is left alone
+		want string
+	}{
+		{name: "chain", steps: []v1alpha1.Step{namedStep("compile"), namedStep("test")}, phase: corev1.PodRunning, value: "2", want: "-2"},
+		{
+			name:  "graph",
+			steps: []v1alpha1.Step{namedStep("compile"), runAfterStep("test", "compile")},
+			phase: corev1.PodRunning,
+			value: "1,2",
+			want:  "1,2,-2",
+		},
+		{name: "already cancelled", steps: []v1alpha1.Step{namedStep("compile")}, phase: corev1.PodRunning, value: "-2"},
+		{name: "finished", steps: []v1alpha1.Step{namedStep("compile")}, phase: corev1.PodSucceeded, value: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(tt.steps...)
+			pm.task.Spec.Status = v1alpha1.OrderStepSpecStatusCancelled
+			recorder := &updateRecorder{}
+			pm.Client = recorder
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotationsOrderField: tt.value}},
+				Status:     corev1.PodStatus{Phase: tt.phase},
+			}
+
+			if err := pm.cancel(context.Background(), pod); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.updated == nil {
+				if len(tt.want) != 0 {
+					t.Errorf("expected the order to become %s, the pod was left alone", tt.want)
+				}
+				return
+			}
+			if got := recorder.updated.GetAnnotations()[annotationsOrderField]; got != tt.want {
+				t.Errorf("expected the order to become %q, got %q", tt.want, got)
+			}
+			if !isTaskCancelled(pod) {
+				t.Error("expected the pod to tell the task was cancelled")
+			}
+		})
+	}
+}
+
+func TestCancelSteps(t *testing.T) {
+	now := metav1.Now()
+	finished := &metav1.Time{Time: now.Add(-time.Minute)}
+	steps := []v1alpha1.StepStatus{
+		{Name: "compile", Reason: v1alpha1.StepReasonCompleted, FinishedAt: finished},
+		{Name: "lint", Reason: v1alpha1.StepReasonFailed, FinishedAt: finished},
+		{Name: "test", Reason: v1alpha1.StepReasonRunning},
+		{Name: "release", Reason: v1alpha1.StepReasonAwaitingApproval},
+		{Name: "deploy", Reason: v1alpha1.StepReasonWaiting},
+		{Name: "notify", Reason: v1alpha1.StepReasonSkipped, FinishedAt: finished},
+	}
+	cancelSteps(steps, now)
+
+	want := []string{
+		v1alpha1.StepReasonCompleted,
+		v1alpha1.StepReasonFailed,
+		v1alpha1.StepReasonCancelled,
+		v1alpha1.StepReasonCancelled,
+		v1alpha1.StepReasonCancelled,
+		v1alpha1.StepReasonSkipped,
+	}
+	for i, s := range steps {
+		if s.Reason != want[i] {
+			t.Errorf("expected step %s to be %s, got %s", s.Name, want[i], s.Reason)
+		}
+		if s.FinishedAt == nil {
+			t.Errorf("expected step %s to be finished", s.Name)
+		} else if want[i] != v1alpha1.StepReasonCancelled && !s.FinishedAt.Equal(finished) {
+			t.Errorf("expected step %s to keep when it finished, got %s", s.Name, s.FinishedAt)
+		}
+	}
+}
+
+func TestPaused(t *testing.T) {
+	pm := newTestPodManager(namedStep("compile"), namedStep("test"), namedStep("deploy"))
+	pm.task.Spec.Status = v1alpha1.OrderStepSpecStatusPaused
+	recorder := &updateRecorder{}
+	pm.Client = recorder
+	pod := taskPod(corev1.PodRunning, "2", map[string]corev1.ContainerState{
+		"compile": exited(0, ""),
+		"test":    exited(0, ""),
+		"deploy":  running(),
+	})
+
+	if next, ok := pm.followingOrder(2, pod); ok {
+		t.Errorf("expected the order to stay on the finished step, got %s", next)
+	}
+	if err := pm.progress(context.Background(), pod); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.updated != nil || pod.Annotations[annotationsOrderField] != "2" {
+		t.Errorf("expected the pod to be left alone, got order %s", pod.Annotations[annotationsOrderField])
+	}
+
+	status := pm.ComputeStatus(pod)
+	if status.Phase != v1alpha1.OrderStepPaused {
+		t.Errorf("expected phase %s, got %s", v1alpha1.OrderStepPaused, status.Phase)
+	}
+	want := []string{v1alpha1.StepReasonCompleted, v1alpha1.StepReasonCompleted, v1alpha1.StepReasonWaiting}
+	for i, s := range status.Steps {
+		if s.Reason != want[i] {
+			t.Errorf("expected step %s to be %s, got %s", s.Name, want[i], s.Reason)
+		}
+	}
+
+	// the order moves on once resumed
+	pm.task.Spec.Status = ""
+	if next, ok := pm.followingOrder(2, pod); !ok || next != "3" {
+		t.Errorf("expected the order to move to 3, got %q/%t", next, ok)
+	}
+}
@@ -0,0 +1,282 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// readySetSkipPrefix marks an order of the ready set whose step must exit as skipped.
+	readySetSkipPrefix = "!"
+)
+
+// readySet is what the order annotation holds when the steps form a graph: the comma separated
+// orders of the steps released to run, the ones to skip prefixed with !, -1 once the task aborted
+// and -2 once it was cancelled.
+type readySet struct {
+	run       map[int]bool
+	skip      map[int]bool
+	aborted   bool
+	cancelled bool
+}
+
+func parseReadySet(value string) readySet {
+	set := readySet{run: make(map[int]bool), skip: make(map[int]bool)}
+	for _, token := range strings.Split(value, ",") {
+		if token == annotationTaskExistValue {
+			set.aborted = true
+			continue
+		}
+		if token == annotationTaskCancelledValue {
+			set.cancelled = true
+			continue
+		}
+		skip := strings.HasPrefix(token, readySetSkipPrefix)
+		order, err := strconv.Atoi(strings.TrimPrefix(token, readySetSkipPrefix))
+		if err != nil || order < 1 {
+			continue
+		}
+		if skip {
+			set.skip[order] = true
+		} else {
+			set.run[order] = true
+		}
+	}
+	return set
+}
+
+func (set readySet) String() string {
+	orders := make([]int, 0, len(set.run)+len(set.skip))
+	for order := range set.run {
+		orders = append(orders, order)
+	}
+	for order := range set.skip {
+		orders = append(orders, order)
+	}
+	sort.Ints(orders)
+	tokens := make([]string, 0, len(orders)+2)
+	for _, order := range orders {
+		if set.skip[order] {
+			tokens = append(tokens, readySetSkipPrefix+strconv.Itoa(order))
+		} else {
+			tokens = append(tokens, strconv.Itoa(order))
+		}
+	}
+	if set.aborted {
+		tokens = append(tokens, annotationTaskExistValue)
+	}
+	if set.cancelled {
+		tokens = append(tokens, annotationTaskCancelledValue)
+	}
+	if len(tokens) == 0 {
+		return annotationsOrderInitialValue
+	}
+	return strings.Join(tokens, ",")
+}
+
+// isDAG reports whether the steps form a graph through runAfter or parallel groups instead of a chain.
+func (pm *PodManager) isDAG() bool {
+	if pm.task.Spec.ExecutionMode == v1alpha1.ExecutionModePodPerStep {
+		return false
+	}
+	for _, step := range pm.task.Spec.Steps {
+		if len(step.RunAfter) != 0 || len(step.Parallel) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// dependency is what a step waits for: all the steps, or any of them once a parallel group
+// completing on any of its members precedes it.
+type dependency struct {
+	steps []int
+	any   bool
+}
+
+func (d dependency) satisfied(done []bool) bool {
+	if len(d.steps) == 0 {
+		return true
+	}
+	for _, j := range d.steps {
+		if d.any && done[j] {
+			return true
+		}
+		if !d.any && !done[j] {
+			return false
+		}
+	}
+	return !d.any
+}
+
+// predecessors returns what every step waits for, the first finally step waits for all
+// the steps and the next ones for the finally step before them.
+func (pm *PodManager) predecessors() []dependency {
+	steps := pm.task.Spec.Steps
+	deps := make([]dependency, 0, len(steps)+len(pm.task.Spec.Finally))
+	if pm.hasRunAfter() {
+		indexes := make(map[string]int, len(steps))
+		for i, step := range steps {
+			indexes[StepName(i, step)] = i
+		}
+		for _, step := range steps {
+			var d dependency
+			for _, name := range step.RunAfter {
+				if j, ok := indexes[name]; ok {
+					d.steps = append(d.steps, j)
+				}
+			}
+			deps = append(deps, d)
+		}
+	} else {
+		// a chain of units, a unit being a single step or a group of adjacent steps
+		var previous dependency
+		for i := 0; i < len(steps); {
+			end := i + 1
+			if group := steps[i].Parallel; len(group) != 0 {
+				for end < len(steps) && steps[end].Parallel == group {
+					end++
+				}
+			}
+			unit := dependency{any: pm.groupCompletion(steps[i].Parallel) == v1alpha1.GroupCompletionAny}
+			for j := i; j < end; j++ {
+				deps = append(deps, previous)
+				unit.steps = append(unit.steps, j)
+			}
+			previous, i = unit, end
+		}
+	}
+
+	for i := range pm.task.Spec.Finally {
+		if i == 0 {
+			all := dependency{}
+			for j := range steps {
+				all.steps = append(all.steps, j)
+			}
+			deps = append(deps, all)
+			continue
+		}
+		deps = append(deps, dependency{steps: []int{len(steps) + i - 1}})
+	}
+	return deps
+}
+
+func (pm *PodManager) hasRunAfter() bool {
+	for _, step := range pm.task.Spec.Steps {
+		if len(step.RunAfter) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// groupCompletion returns the completion of the parallel group, all unless configured otherwise.
+func (pm *PodManager) groupCompletion(group string) v1alpha1.GroupCompletion {
+	if len(group) == 0 {
+		return v1alpha1.GroupCompletionAll
+	}
+	for _, g := range pm.task.Spec.ParallelGroups {
+		if g.Name == group && len(g.Completion) != 0 {
+			return g.Completion
+		}
+	}
+	return v1alpha1.GroupCompletionAll
+}
+
+// forwardDAG releases every step whose predecessors are done, a main step failing skips the steps
+// not released yet and aborts the task when there is no finally step.
+func (pm *PodManager) forwardDAG(ctx context.Context, pod *corev1.Pod) error {
+	value := pod.Annotations[annotationsOrderField]
+	if value == annotationsOrderInitialValue && pod.Status.Phase != corev1.PodRunning {
+		return nil
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return nil
+	}
+	set := parseReadySet(value)
+	if set.aborted {
+		return nil
+	}
+
+	steps := pm.allSteps()
+	preds := pm.predecessors()
+	failed := false
+	done := make([]bool, len(steps))
+	for i, step := range steps {
+		order := i + 1
+		if set.skip[order] {
+			done[i] = true
+			continue
+		}
+		if !set.run[order] {
+			continue
+		}
+		cs, ok := getContainerStatus(pod, StepContainerName(i, step))
+		if !ok || cs.State.Terminated == nil {
+			continue
+		}
+		done[i] = true
+		if cs.State.Terminated.ExitCode != 0 && step.OnError != v1alpha1.OnErrorContinue && i < len(pm.task.Spec.Steps) {
+			failed = true
+		}
+	}
+
+	var vars map[string]string
+	changed := false
+	// skipping a step may release the ones after it, until nothing moves
+	for moved := true; moved; {
+		moved = false
+		for i, step := range steps {
+			order := i + 1
+			if set.run[order] || set.skip[order] {
+				continue
+			}
+			if failed && i < len(pm.task.Spec.Steps) {
+				set.skip[order], done[i], moved = true, true, true
+				continue
+			}
+			if !preds[i].satisfied(done) {
+				continue
+			}
+			if len(step.When) != 0 {
+				if vars == nil {
+					vars = pm.variables(pod)
+				}
+				if !whenHolds(step, vars) {
+					set.skip[order], done[i], moved = true, true, true
+					continue
+				}
+			}
+			if pm.held(order) {
+				continue
+			}
+			set.run[order], moved = true, true
+		}
+		changed = changed || moved
+	}
+	if failed && len(pm.task.Spec.Finally) == 0 {
+		set.aborted, changed = true, true
+	}
+	if !changed {
+		return nil
+	}
+	pod.Annotations[annotationsOrderField] = set.String()
+	return pm.Client.Update(ctx, pod)
+}
+
+// isTaskAborted reports whether the order annotation of the pod tells the task aborted.
+func isTaskAborted(pod *corev1.Pod) bool {
+	if pod == nil {
+		return false
+	}
+	for _, token := range strings.Split(pod.Annotations[annotationsOrderField], ",") {
+		if token == annotationTaskExistValue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+)
+
+// updateRecorder is a client keeping the last object updated, the other calls are not expected.
+type updateRecorder struct {
+	client.Client
+	updated client.Object
+}
+
+func (r *updateRecorder) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	r.updated = obj
+	return nil
+}
+
+func runAfterStep(name string, runAfter ...string) v1alpha1.Step {
+	step := namedStep(name)
+	step.RunAfter = runAfter
+	return step
+}
+
+func TestReadySet(t *testing.T) {
+	tests := []struct {
+		value string
+		want  readySet
+		// wantString differs from value when it is not written the canonical way
+		wantString string
+	}{
+		{value: "0", want: readySet{run: map[int]bool{}, skip: map[int]bool{}}},
+		{value: "1,2", want: readySet{run: map[int]bool{1: true, 2: true}, skip: map[int]bool{}}},
+		{value: "1,!2,3", want: readySet{run: map[int]bool{1: true, 3: true}, skip: map[int]bool{2: true}}},
+		{value: "1,!2,-1", want: readySet{run: map[int]bool{1: true}, skip: map[int]bool{2: true}, aborted: true}},
+		{value: "1,-2", want: readySet{run: map[int]bool{1: true}, skip: map[int]bool{}, cancelled: true}},
+		{value: "3,1,x", want: readySet{run: map[int]bool{1: true, 3: true}, skip: map[int]bool{}}, wantString: "1,3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := parseReadySet(tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+			wantString := tt.wantString
+			if len(wantString) == 0 {
+				wantString = tt.value
+			}
+			if s := got.String(); s != wantString {
+				t.Errorf("expected it to be written %s, got %s", wantString, s)
+			}
+		})
+	}
+}
+
+func TestDependencySatisfied(t *testing.T) {
+	done := []bool{true, false, true}
+	tests := []struct {
+		name       string
+		dependency dependency
+		want       bool
+	}{
+		{name: "none", want: true},
+		{name: "all done", dependency: dependency{steps: []int{0, 2}}, want: true},
+		{name: "all not done", dependency: dependency{steps: []int{0, 1}}},
+		{name: "any done", dependency: dependency{steps: []int{1, 2}, any: true}, want: true},
+		{name: "any not done", dependency: dependency{steps: []int{1}, any: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dependency.satisfied(done); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPredecessors(t *testing.T) {
+	pm := newTestPodManager(namedStep("checkout"), runAfterStep("lint", "checkout"), runAfterStep("test", "checkout"),
+		runAfterStep("publish", "lint", "test"))
+	pm.task.Spec.Finally = []v1alpha1.Step{namedStep("cleanup"), namedStep("notify")}
+	want := []dependency{
+		{},
+		{steps: []int{0}},
+		{steps: []int{0}},
+		{steps: []int{1, 2}},
+		// the finally steps wait for all the steps, then for each other
+		{steps: []int{0, 1, 2, 3}},
+		{steps: []int{4}},
+	}
+	if got := pm.predecessors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestForwardDAG(t *testing.T) {
+	tests := []struct {
+		name      string
+		finally   bool
+		value     string
+		exitCodes map[string]int32
+		// want is the annotation written, empty when the pod is left alone
+		want string
+	}{
+		{name: "start", value: "0", want: "1"},
+		{name: "running", value: "1"},
+		{name: "fan out", value: "1", exitCodes: map[string]int32{"checkout": 0}, want: "1,2,3"},
+		{name: "fan in waits", value: "1,2,3", exitCodes: map[string]int32{"checkout": 0, "lint": 0}},
+		{name: "fan in", value: "1,2,3", exitCodes: map[string]int32{"checkout": 0, "lint": 0, "test": 0}, want: "1,2,3,4"},
+		{
+			name:      "failed",
+			value:     "1,2,3",
+			exitCodes: map[string]int32{"checkout": 0, "lint": 1},
+			want:      "1,2,3,!4,-1",
+		},
+		{
+			name:      "failed before the finally step",
+			finally:   true,
+			value:     "1,2,3",
+			exitCodes: map[string]int32{"checkout": 0, "lint": 1, "test": 0},
+			want:      "1,2,3,!4,5",
+		},
+		{name: "aborted", value: "1,2,3,!4,-1", exitCodes: map[string]int32{"checkout": 0, "lint": 1, "test": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(namedStep("checkout"), runAfterStep("lint", "checkout"), runAfterStep("test", "checkout"),
+				runAfterStep("publish", "lint", "test"))
+			if tt.finally {
+				pm.task.Spec.Finally = []v1alpha1.Step{namedStep("cleanup")}
+			}
+			recorder := &updateRecorder{}
+			pm.Client = recorder
+			pod := terminatedPod(tt.exitCodes)
+			pod.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{annotationsOrderField: tt.value}}
+			pod.Status.Phase = corev1.PodRunning
+
+			if err := pm.forwardDAG(context.Background(), pod); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.updated == nil {
+				if len(tt.want) != 0 {
+					t.Errorf("expected the order to become %s, the pod was left alone", tt.want)
+				}
+				return
+			}
+			if got := recorder.updated.GetAnnotations()[annotationsOrderField]; got != tt.want {
+				t.Errorf("expected the order to become %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func parallelStep(name, group string) v1alpha1.Step {
+	step := namedStep(name)
+	step.Parallel = group
+	return step
+}
+
+func TestPredecessorsParallel(t *testing.T) {
+	tests := []struct {
+		name       string
+		completion v1alpha1.GroupCompletion
+		want       []dependency
+	}{
+		{
+			name: "all",
+			want: []dependency{{}, {steps: []int{0}}, {steps: []int{0}}, {steps: []int{1, 2}}},
+		},
+		{
+			name:       "any",
+			completion: v1alpha1.GroupCompletionAny,
+			want:       []dependency{{}, {steps: []int{0}}, {steps: []int{0}}, {steps: []int{1, 2}, any: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(namedStep("checkout"), parallelStep("mirror-a", "mirrors"), parallelStep("mirror-b", "mirrors"),
+				namedStep("publish"))
+			if len(tt.completion) != 0 {
+				pm.task.Spec.ParallelGroups = []v1alpha1.ParallelGroup{{Name: "mirrors", Completion: tt.completion}}
+			}
+			if got := pm.predecessors(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
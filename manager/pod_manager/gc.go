@@ -0,0 +1,152 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+const (
+	logTailLines  = 20
+	logLimitBytes = 4096
+)
+
+// GCOptions are the operator wide defaults of the garbage collection of finished tasks,
+// what the task sets itself takes precedence.
+type GCOptions struct {
+	TTLSecondsAfterFinished *int32
+	DeleteAfterTTL          bool
+}
+
+// TTLExpiresIn returns how long until the ttl of the task finished with status expires and whether the
+// task itself is deleted then, ok is false while it runs or when its pods are kept forever.
+func (pm *PodManager) TTLExpiresIn(options GCOptions, status *v1alpha1.OrderStepStatus, now time.Time) (left time.Duration, deleteTask bool, ok bool) {
+	seconds := pm.task.Spec.TTLSecondsAfterFinished
+	if seconds == nil {
+		seconds = options.TTLSecondsAfterFinished
+	}
+	if seconds == nil || status.CompletionTime == nil {
+		return 0, false, false
+	}
+	deleteTask = options.DeleteAfterTTL
+	if pm.task.Spec.DeleteAfterTTL != nil {
+		deleteTask = *pm.task.Spec.DeleteAfterTTL
+	}
+	expiry := status.CompletionTime.Add(time.Duration(*seconds) * time.Second)
+	return expiry.Sub(now), deleteTask, true
+}
+
+// RecordLogs sets the tail of the output of every finished step in the status, the steps whose pod
+// is already gone keep what was recorded.
+func (pm *PodManager) RecordLogs(ctx context.Context, kubeCli kubernetes.Interface, status *v1alpha1.OrderStepStatus) error {
+	pods, err := pm.podsByStep(ctx)
+	if err != nil {
+		return err
+	}
+	for i, step := range pm.allSteps() {
+		var stepStatus *v1alpha1.StepStatus
+		switch {
+		case i < len(status.Steps):
+			stepStatus = &status.Steps[i]
+		case i-len(status.Steps) < len(status.Finally):
+			stepStatus = &status.Finally[i-len(status.Steps)]
+		}
+		pod := pods[i]
+		if stepStatus == nil || pod == nil {
+			continue
+		}
+		container := StepContainerName(i, step)
+		if cs, ok := getContainerStatus(pod, container); !ok || cs.State.Terminated == nil {
+			continue
+		}
+		logs, err := kubeCli.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container:  container,
+			TailLines:  pointer.Int64(logTailLines),
+			LimitBytes: pointer.Int64(logLimitBytes),
+		}).DoRaw(ctx)
+		if err != nil {
+			if k8s_utils.IsKubernetesResourceNotExist(err) {
+				continue
+			}
+			return err
+		}
+		stepStatus.Logs = string(logs)
+	}
+	return nil
+}
+
+// DeleteTaskPods deletes the pods running the steps, or the Job and its pods, deleted is false
+// when they were already gone.
+func (pm *PodManager) DeleteTaskPods(ctx context.Context) (deleted bool, err error) {
+	var objects []client.Object
+	switch pm.task.Spec.ExecutionMode {
+	case v1alpha1.ExecutionModePodPerStep:
+		pods, err := pm.getStepPods(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, pod := range pods {
+			if pod != nil {
+				objects = append(objects, pod)
+			}
+		}
+	case v1alpha1.ExecutionModeJob:
+		job, err := pm.GetChildJob(ctx)
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return false, err
+		} else if err == nil {
+			objects = append(objects, job)
+		}
+	default:
+		pod, err := pm.GetChildPod(ctx)
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return false, err
+		} else if err == nil {
+			objects = append(objects, pod)
+		}
+	}
+
+	for _, obj := range objects {
+		err = pm.Client.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return deleted, err
+		}
+		deleted = deleted || err == nil
+	}
+	return deleted, nil
+}
+
+// podsByStep returns the pod that ran every step by index, nil for the ones without.
+func (pm *PodManager) podsByStep(ctx context.Context) ([]*corev1.Pod, error) {
+	steps := pm.allSteps()
+	var pod *corev1.Pod
+	switch pm.task.Spec.ExecutionMode {
+	case v1alpha1.ExecutionModePodPerStep:
+		return pm.getStepPods(ctx)
+	case v1alpha1.ExecutionModeJob:
+		job, err := pm.GetChildJob(ctx)
+		if err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return nil, err
+		} else if err == nil {
+			if pod, err = pm.getJobPod(ctx, job); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		var err error
+		if pod, err = pm.GetChildPod(ctx); err != nil && !k8s_utils.IsKubernetesResourceNotExist(err) {
+			return nil, err
+		}
+	}
+	pods := make([]*corev1.Pod, len(steps))
+	for i := range pods {
+		pods[i] = pod
+	}
+	return pods, nil
+}
@@ -0,0 +1,149 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+func TestTTLExpiresIn(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	finished := &metav1.Time{Time: now.Add(-time.Minute)}
+	tests := []struct {
+		name           string
+		ttl            *int32
+		deleteAfterTTL *bool
+		options        GCOptions
+		completion     *metav1.Time
+		wantLeft       time.Duration
+		wantDelete     bool
+		wantOk         bool
+	}{
+		{name: "no ttl", completion: finished},
+		{name: "running", ttl: pointer.Int32(60), options: GCOptions{TTLSecondsAfterFinished: pointer.Int32(60)}},
+		{name: "operator default", options: GCOptions{TTLSecondsAfterFinished: pointer.Int32(300), DeleteAfterTTL: true}, completion: finished, wantLeft: 4 * time.Minute, wantDelete: true, wantOk: true},
+		{name: "task overrides", ttl: pointer.Int32(30), deleteAfterTTL: pointer.Bool(false), options: GCOptions{TTLSecondsAfterFinished: pointer.Int32(300), DeleteAfterTTL: true}, completion: finished, wantLeft: -30 * time.Second, wantOk: true},
+		{name: "zero", ttl: pointer.Int32(0), completion: finished, wantLeft: -time.Minute, wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(namedStep("compile"))
+			pm.task.Spec.TTLSecondsAfterFinished = tt.ttl
+			pm.task.Spec.DeleteAfterTTL = tt.deleteAfterTTL
+			left, deleteTask, ok := pm.TTLExpiresIn(tt.options, &v1alpha1.OrderStepStatus{CompletionTime: tt.completion}, now)
+			if left != tt.wantLeft || deleteTask != tt.wantDelete || ok != tt.wantOk {
+				t.Errorf("expected (%v, %v, %v), got (%v, %v, %v)", tt.wantLeft, tt.wantDelete, tt.wantOk, left, deleteTask, ok)
+			}
+		})
+	}
+}
+
+var executionModes = map[string]v1alpha1.ExecutionMode{
+	"pod":        "",
+	"podPerStep": v1alpha1.ExecutionModePodPerStep,
+	"job":        v1alpha1.ExecutionModeJob,
+}
+
+// gcObjects returns what an OrderStep run in the given mode left behind once its compile step finished,
+// along with a pod of another Job which must be left alone.
+func gcObjects(mode v1alpha1.ExecutionMode) []client.Object {
+	named := func(pod *corev1.Pod, name string) *corev1.Pod {
+		pod.Name, pod.Namespace = name, "default"
+		return pod
+	}
+	stranger := named(taskPod(corev1.PodSucceeded, "3", map[string]corev1.ContainerState{
+		"compile": exited(0, ""),
+		"test":    exited(0, ""),
+	}), "stranger")
+	stranger.Labels = map[string]string{jobControllerUIDLabel: "other-uid"}
+	stranger.CreationTimestamp = metav1.NewTime(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC))
+
+	base := GenerateBaseName("build")
+	switch mode {
+	case v1alpha1.ExecutionModePodPerStep:
+		return []client.Object{stranger, named(taskPod(corev1.PodSucceeded, "1", map[string]corev1.ContainerState{
+			"compile": exited(0, ""),
+		}), StepPodName(base, 0, commandStep("compile")))}
+	case v1alpha1.ExecutionModeJob:
+		pod := jobPod("build-1", 0, corev1.PodRunning, "2")
+		pod.Status.ContainerStatuses = taskPod(corev1.PodRunning, "2", map[string]corev1.ContainerState{
+			"compile": exited(0, ""),
+			"test":    running(),
+		}).Status.ContainerStatuses
+		return []client.Object{stranger, testJob(0), pod}
+	default:
+		return []client.Object{stranger, named(taskPod(corev1.PodRunning, "2", map[string]corev1.ContainerState{
+			"compile": exited(0, ""),
+			"test":    running(),
+		}), base)}
+	}
+}
+
+func TestDeleteTaskPods(t *testing.T) {
+	for name, mode := range executionModes {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			objs := gcObjects(mode)
+			pm := newFakePodManager(mode, objs...)
+
+			deleted, err := pm.DeleteTaskPods(ctx)
+			if err != nil || !deleted {
+				t.Fatalf("expected the pods to be deleted, got %t/%v", deleted, err)
+			}
+			for _, obj := range objs {
+				err := pm.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+				switch {
+				case obj.GetName() == "stranger" && err != nil:
+					t.Errorf("expected the pod of another Job to be left alone, got %v", err)
+				case mode == v1alpha1.ExecutionModeJob && obj.GetName() == "build-1":
+					// the pods of the Job are deleted along with it by the garbage collector
+				case obj.GetName() != "stranger" && !apierrors.IsNotFound(err):
+					t.Errorf("expected %s to be deleted, got %v", obj.GetName(), err)
+				}
+			}
+
+			if deleted, err = pm.DeleteTaskPods(ctx); err != nil || deleted {
+				t.Errorf("expected nothing left to delete, got %t/%v", deleted, err)
+			}
+		})
+	}
+}
+
+func TestRecordLogs(t *testing.T) {
+	for name, mode := range executionModes {
+		t.Run(name, func(t *testing.T) {
+			pm := newFakePodManager(mode, gcObjects(mode)...)
+			status := &v1alpha1.OrderStepStatus{Steps: []v1alpha1.StepStatus{
+				{Name: "compile"},
+				// recorded before, the step has not finished since
+				{Name: "test", Logs: "recorded"},
+			}}
+
+			kubeCli := kubefake.NewSimpleClientset()
+			if err := pm.RecordLogs(context.Background(), kubeCli, status); err != nil {
+				t.Fatal(err)
+			}
+			if status.Steps[0].Logs != "fake logs" || status.Steps[1].Logs != "recorded" {
+				t.Errorf("expected only the logs of the finished step, got %q and %q", status.Steps[0].Logs, status.Steps[1].Logs)
+			}
+			var containers []string
+			for _, action := range kubeCli.Actions() {
+				if opts, ok := action.(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions); ok {
+					containers = append(containers, opts.Container)
+				}
+			}
+			if !reflect.DeepEqual(containers, []string{"compile"}) {
+				t.Errorf("expected the logs of compile to be read, got %v", containers)
+			}
+		})
+	}
+}
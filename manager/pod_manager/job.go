@@ -0,0 +1,166 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// jobControllerUIDLabel is set by the Job controller on the pods it creates.
+	jobControllerUIDLabel = "controller-uid"
+	// jobReasonDeadlineExceeded is the reason of the Failed condition of a Job past its activeDeadlineSeconds
+	jobReasonDeadlineExceeded = "DeadlineExceeded"
+)
+
+// buildJob creates the Job running the pod of the steps, then moves the order on in its current pod.
+func (pm *PodManager) buildJob(ctx context.Context) error {
+	job, err := pm.GetChildJob(ctx)
+	if err == nil {
+		if isJobFinished(job) {
+			return nil
+		}
+		pod, err := pm.getJobPod(ctx, job)
+		if err != nil || pod == nil {
+			return err
+		}
+		// the Job must not replace the cancelled pod, it is only suspended once the pod
+		// finished as suspending deletes the running pods without their steps reporting
+		if pm.isCancelRequested() && isTaskCancelled(pod) &&
+			(pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed) {
+			if job.Spec.Suspend != nil && *job.Spec.Suspend {
+				return nil
+			}
+			job.Spec.Suspend = pointer.Bool(true)
+			return pm.Client.Update(ctx, job)
+		}
+		return pm.progress(ctx, pod)
+	}
+	if !k8s_utils.IsKubernetesResourceNotExist(err) {
+		return err
+	}
+	// the Job is gone after its ttlSecondsAfterFinished, it must not run again
+	if pm.task.Status.CompletionTime != nil || pm.isCancelRequested() {
+		return nil
+	}
+
+	if err = pm.createDependencies(ctx); err != nil {
+		return err
+	}
+	pm.setTaskPod()
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: pm.pod.Annotations,
+		},
+		Spec: pm.pod.Spec,
+	}
+	// the deadline bounds the Job and its retries, not every pod
+	activeDeadlineSeconds := template.Spec.ActiveDeadlineSeconds
+	template.Spec.ActiveDeadlineSeconds = nil
+
+	job = &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pm.baseName,
+			Namespace:       pm.task.GetNamespace(),
+			OwnerReferences: pm.ownerReferences(),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          pointer.Int32(0),
+			ActiveDeadlineSeconds: activeDeadlineSeconds,
+			Template:              template,
+		},
+	}
+	if options := pm.task.Spec.Job; options != nil {
+		if options.BackoffLimit != nil {
+			job.Spec.BackoffLimit = pointer.Int32(*options.BackoffLimit)
+		}
+		if options.TTLSecondsAfterFinished != nil {
+			job.Spec.TTLSecondsAfterFinished = pointer.Int32(*options.TTLSecondsAfterFinished)
+		}
+	}
+	err = pm.Client.Create(ctx, job)
+	if k8s_utils.IsKubernetesResourceAlreadyExistError(err) {
+		return nil
+	}
+	return err
+}
+
+func (pm *PodManager) GetChildJob(ctx context.Context) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	err := pm.Client.Get(ctx, types.NamespacedName{
+		Namespace: pm.task.Namespace,
+		Name:      pm.baseName}, job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// getJobPod returns the latest pod of the Job, nil until it created one.
+func (pm *PodManager) getJobPod(ctx context.Context, job *batchv1.Job) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	err := pm.Client.List(ctx, pods, client.InNamespace(job.Namespace),
+		client.MatchingLabels{jobControllerUIDLabel: string(job.UID)})
+	if err != nil {
+		return nil, err
+	}
+	var latest *corev1.Pod
+	for i := range pods.Items {
+		if latest == nil || latest.CreationTimestamp.Before(&pods.Items[i].CreationTimestamp) {
+			latest = &pods.Items[i]
+		}
+	}
+	return latest, nil
+}
+
+// jobPodView returns the latest pod of the Job with the phase of the Job: a failed pod
+// the Job is going to replace leaves the OrderStep running.
+func (pm *PodManager) jobPodView(ctx context.Context) (*corev1.Pod, error) {
+	job, err := pm.GetChildJob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pod, err := pm.getJobPod(ctx, job)
+	if err != nil || pod == nil {
+		return nil, err
+	}
+
+	view := pod.DeepCopy()
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			view.Status.Phase = corev1.PodSucceeded
+			return view, nil
+		case batchv1.JobFailed:
+			view.Status.Phase = corev1.PodFailed
+			if cond.Reason == jobReasonDeadlineExceeded {
+				view.Status.Reason = podReasonDeadlineExceeded
+			}
+			return view, nil
+		}
+	}
+	if view.Status.Phase == corev1.PodFailed {
+		view.Status.Phase = corev1.PodRunning
+		if isTaskAborted(view) {
+			view.Annotations[annotationsOrderField] = annotationsOrderInitialValue
+		}
+	}
+	return view, nil
+}
+
+func isJobFinished(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if (cond.Type == batchv1.JobComplete || cond.Type == batchv1.JobFailed) && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func TestIsJobFinished(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []batchv1.JobCondition
+		want       bool
+	}{
+		{name: "running"},
+		{name: "suspended", conditions: []batchv1.JobCondition{{Type: batchv1.JobSuspended, Status: corev1.ConditionTrue}}},
+		{name: "complete", conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}, want: true},
+		{name: "failed", conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}, want: true},
+		{name: "no longer failed", conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionFalse}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &batchv1.Job{Status: batchv1.JobStatus{Conditions: tt.conditions}}
+			if got := isJobFinished(job); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+// newFakePodManager returns the PodManager of an OrderStep run in the given mode, the client serves objs.
+func newFakePodManager(mode v1alpha1.ExecutionMode, objs ...client.Object) *PodManager {
+	task := &v1alpha1.OrderStep{
+		ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "default", UID: "build-uid"},
+		Spec: v1alpha1.OrderStepSpec{
+			Steps:          []v1alpha1.Step{commandStep("compile"), commandStep("test")},
+			ExecutionMode:  mode,
+			ActiveDeadline: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+	return NewPodManager(task, fake.NewClientBuilder().WithObjects(objs...).Build(), nil)
+}
+
+func commandStep(name string) v1alpha1.Step {
+	step := namedStep(name)
+	step.Image, step.Command = "alpine:3.18", []string{"true"}
+	return step
+}
+
+func testJob(backoffLimit int32, conditions ...batchv1.JobCondition) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: GenerateBaseName("build"), Namespace: "default", UID: "job-uid"},
+		Spec:       batchv1.JobSpec{BackoffLimit: pointer.Int32(backoffLimit)},
+		Status:     batchv1.JobStatus{Conditions: conditions},
+	}
+}
+
+// jobPod returns a pod of the Job created at the given minute.
+func jobPod(name string, minute int, phase corev1.PodPhase, order string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            map[string]string{jobControllerUIDLabel: "job-uid"},
+			Annotations:       map[string]string{annotationsOrderField: order},
+			CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC)),
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestJobPodView(t *testing.T) {
+	failed := func(reason string) batchv1.JobCondition {
+		return batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: reason}
+	}
+	tests := []struct {
+		name        string
+		job         *batchv1.Job
+		pods        []*corev1.Pod
+		wantPod     string
+		wantOrder   string
+		wantReason  string
+		wantPhase   v1alpha1.OrderStepPhase
+		wantTimeout bool
+	}{
+		{
+			// the Job replaces the pod, the steps run again from the start
+			name:      "failed pod retried",
+			job:       testJob(2),
+			pods:      []*corev1.Pod{jobPod("build-1", 0, corev1.PodFailed, annotationTaskExistValue)},
+			wantPod:   "build-1",
+			wantOrder: annotationsOrderInitialValue,
+			wantPhase: v1alpha1.OrderStepRunning,
+		},
+		{
+			name: "latest pod",
+			job:  testJob(2),
+			pods: []*corev1.Pod{
+				jobPod("build-2", 1, corev1.PodRunning, "1"),
+				jobPod("build-1", 0, corev1.PodFailed, annotationTaskExistValue),
+			},
+			wantPod:   "build-2",
+			wantOrder: "1",
+			wantPhase: v1alpha1.OrderStepRunning,
+		},
+		{
+			name:      "backoffLimit exhausted",
+			job:       testJob(1, failed("BackoffLimitExceeded")),
+			pods:      []*corev1.Pod{jobPod("build-1", 0, corev1.PodFailed, annotationTaskExistValue)},
+			wantPod:   "build-1",
+			wantOrder: annotationTaskExistValue,
+			wantPhase: v1alpha1.OrderStepFailed,
+		},
+		{
+			// the pod being killed may still be reported running
+			name:        "deadline exceeded",
+			job:         testJob(0, failed(jobReasonDeadlineExceeded)),
+			pods:        []*corev1.Pod{jobPod("build-1", 0, corev1.PodRunning, "2")},
+			wantPod:     "build-1",
+			wantOrder:   "2",
+			wantReason:  podReasonDeadlineExceeded,
+			wantPhase:   v1alpha1.OrderStepFailed,
+			wantTimeout: true,
+		},
+		{
+			name:      "complete",
+			job:       testJob(0, batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}),
+			pods:      []*corev1.Pod{jobPod("build-1", 0, corev1.PodSucceeded, "2")},
+			wantPod:   "build-1",
+			wantOrder: "2",
+			wantPhase: v1alpha1.OrderStepSucceeded,
+		},
+		{
+			name: "no pod yet",
+			job:  testJob(0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{tt.job}
+			for _, pod := range tt.pods {
+				objs = append(objs, pod)
+			}
+			pm := newFakePodManager(v1alpha1.ExecutionModeJob, objs...)
+
+			view, err := pm.jobPodView(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if view == nil {
+				if len(tt.wantPod) != 0 {
+					t.Fatalf("expected the view of %s, got none", tt.wantPod)
+				}
+				return
+			}
+			if view.Name != tt.wantPod || view.Annotations[annotationsOrderField] != tt.wantOrder || view.Status.Reason != tt.wantReason {
+				t.Errorf("expected %s at order %s with reason %q, got %s at order %s with reason %q", tt.wantPod, tt.wantOrder,
+					tt.wantReason, view.Name, view.Annotations[annotationsOrderField], view.Status.Reason)
+			}
+			status := pm.ComputeStatus(view)
+			if status.Phase != tt.wantPhase {
+				t.Errorf("expected phase %s, got %s", tt.wantPhase, status.Phase)
+			}
+			condition := meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionSucceeded)
+			if timedOut := condition.Reason == v1alpha1.StepReasonTimedOut; timedOut != tt.wantTimeout {
+				t.Errorf("expected timed out %t, got condition %+v", tt.wantTimeout, condition)
+			}
+		})
+	}
+}
+
+func TestBuildJob(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("created", func(t *testing.T) {
+		pm := newFakePodManager(v1alpha1.ExecutionModeJob)
+		pm.task.Spec.Job = &v1alpha1.JobOptions{BackoffLimit: pointer.Int32(2)}
+		if err := pm.buildJob(ctx); err != nil {
+			t.Fatal(err)
+		}
+		job, err := pm.GetChildJob(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *job.Spec.BackoffLimit != 2 || job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != 3600 {
+			t.Errorf("expected a backoffLimit of 2 and the deadline on the Job, got %+v", job.Spec)
+		}
+		template := job.Spec.Template
+		if template.Spec.ActiveDeadlineSeconds != nil {
+			t.Error("expected the deadline to bound the Job, not every pod")
+		}
+		if template.Annotations[v1alpha1.OrderStepNameAnnotation] != "build" {
+			t.Errorf("expected the pods to lead back to the OrderStep, got %v", template.Annotations)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		wantSuspend bool
+		wantOrder   string
+	}{
+		{
+			// the entrypoints terminate the steps first, suspending deletes the pod
+			name:      "cancelled while running",
+			pod:       jobPod("build-1", 0, corev1.PodRunning, "1"),
+			wantOrder: annotationTaskCancelledValue,
+		},
+		{
+			name:        "suspended once cancelled",
+			pod:         jobPod("build-1", 0, corev1.PodFailed, annotationTaskCancelledValue),
+			wantSuspend: true,
+			wantOrder:   annotationTaskCancelledValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newFakePodManager(v1alpha1.ExecutionModeJob, testJob(2), tt.pod)
+			pm.task.Spec.Status = v1alpha1.OrderStepSpecStatusCancelled
+			if err := pm.buildJob(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := pm.GetChildJob(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if suspended := job.Spec.Suspend != nil && *job.Spec.Suspend; suspended != tt.wantSuspend {
+				t.Errorf("expected suspended %t, got %t", tt.wantSuspend, suspended)
+			}
+			pod := &corev1.Pod{}
+			if err = pm.Client.Get(ctx, client.ObjectKeyFromObject(tt.pod), pod); err != nil {
+				t.Fatal(err)
+			}
+			if order := pod.Annotations[annotationsOrderField]; order != tt.wantOrder {
+				t.Errorf("expected order %s, got %s", tt.wantOrder, order)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"path"
+)
+
+// paramVariables returns the values of the declared params keyed by params.<name>,
+// string params and array params apart.
+func (pm *PodManager) paramVariables() (map[string]string, map[string][]string) {
+	vars := make(map[string]string)
+	arrays := make(map[string][]string)
+	for _, param := range pm.task.Spec.Params {
+		if param.Default == nil {
+			continue
+		}
+		if param.Default.Type == v1alpha1.ParamTypeArray {
+			arrays["params."+param.Name] = param.Default.ArrayVal
+		} else {
+			vars["params."+param.Name] = param.Default.StringVal
+		}
+	}
+	return vars, arrays
+}
+
+// applyVariables substitutes $(params.<name>), $(results.<name>.path) and $(workspaces.<name>.path)
+// in the fields of the step at index copied to its container. $(steps.<step>.results.<name>) is only
+// known once the earlier step ran, it is left to the entrypoint.
+func (pm *PodManager) applyVariables(index int, step v1alpha1.Step) v1alpha1.Step {
+	vars, arrays := pm.paramVariables()
+	for name, value := range pm.stepVariables {
+		vars[name] = value
+	}
+	for _, result := range step.Results {
+		vars["results."+result.Name+".path"] = path.Join(resultsRootPath, StepName(index, step), result.Name)
+	}
+	for name, value := range pm.workspaceVariables(step) {
+		vars[name] = value
+	}
+	step = *step.DeepCopy()
+	step.Image = substitution_util.Replace(step.Image, vars)
+	step.Script = substitution_util.Replace(step.Script, vars)
+	step.Command = substitution_util.ReplaceWithArrays(step.Command, vars, arrays)
+	step.Args = substitution_util.ReplaceWithArrays(step.Args, vars, arrays)
+	for i := range step.Env {
+		step.Env[i].Value = substitution_util.Replace(step.Env[i].Value, vars)
+	}
+	return step
+}
@@ -0,0 +1,41 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"reflect"
+	"testing"
+)
+
+func TestApplyVariables(t *testing.T) {
+	step := namedStep("deploy")
+	step.Image = "registry/app:$(params.tag)"
+	step.Command = []string{"deploy"}
+	step.Args = []string{"--env=$(params.env)", "$(params.flags[*])", "$(steps.build.results.digest)"}
+	step.Env = []corev1.EnvVar{{Name: "ENV", Value: "$(params.env)"}}
+
+	pm := newTestPodManager(step)
+	pm.task.Spec.Params = []v1alpha1.ParamSpec{
+		{Name: "env", Default: v1alpha1.NewStringParamValue("prod")},
+		{Name: "tag", Default: v1alpha1.NewStringParamValue("1.0")},
+		{Name: "flags", Type: v1alpha1.ParamTypeArray, Default: v1alpha1.NewArrayParamValue("-v", "--wait")},
+		// a template param left unbound is not substituted
+		{Name: "region"},
+	}
+	got := pm.applyVariables(0, step)
+
+	if got.Image != "registry/app:1.0" {
+		t.Errorf("expected the image to be substituted, got %s", got.Image)
+	}
+	// the results of the earlier steps are left to the entrypoint
+	wantArgs := []string{"--env=prod", "-v", "--wait", "$(steps.build.results.digest)"}
+	if !reflect.DeepEqual(got.Args, wantArgs) {
+		t.Errorf("expected args %v, got %v", wantArgs, got.Args)
+	}
+	if got.Env[0].Value != "prod" {
+		t.Errorf("expected the env to be substituted, got %s", got.Env[0].Value)
+	}
+	if step.Env[0].Value != "$(params.env)" {
+		t.Errorf("expected the step to be left alone, got %s", step.Env[0].Value)
+	}
+}
@@ -6,12 +6,12 @@ import (
 	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
 	image2 "github.com/daicheng123/ordertask-operator/pkg/image"
 	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
-	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/lru"
-	"runtime"
+	"k8s.io/utils/pointer"
+	"math"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
 	"strings"
@@ -28,6 +28,7 @@ type PodManagerInterface interface {
 
 const (
 	orderTaskNamePrefix          = "order-task-"
+	orderRunNamePrefix           = "order-run-"
 	initContainerPath            = "chengdai/entrypoint"
 	annotationsOrderField        = "orderField"
 	annotationsOrderInitialValue = "0"
@@ -36,10 +37,9 @@ const (
 	EntryPointVolume    = "entrypoint-volume"
 	DevopsScriptsVolume = "scripts-volume"
 	PodInfoVolume       = "podinfo"
-)
+	ResultsVolume       = "results-volume"
 
-var (
-	osArch = fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
+	resultsRootPath = "/ordertask/results"
 )
 
 type PodManager struct {
@@ -47,11 +47,22 @@ type PodManager struct {
 	task       *v1alpha1.OrderStep
 	Client     client.Client
 	imageCache *lru.Cache
+
+	// ownerKind is the kind of the object the pods are created for, the OrderStep
+	// itself or the OrderStepRun the task was built for by RunOrderStep.
+	ownerKind string
+	// baseName is what the names of the objects created for the owner start with, the
+	// prefix of a run differs so that they never clash with the ones of an OrderStep.
+	baseName string
+
+	// stepVariables are what is known of the steps that already ran when a step pod is built
+	// in podPerStep mode, their results are substituted instead of left to the entrypoint.
+	stepVariables map[string]string
 }
 
 func (pm *PodManager) setInitContainer() {
 	initContainer := corev1.Container{
-		Name:    generateBaseName(pm.task.GetName()) + "-init",
+		Name:    InitContainerName(pm.baseName),
 		Image:   initContainerPath,
 		Command: []string{"cp", "/app/entrypoint", "/entrypoint/bin/"},
 		VolumeMounts: []corev1.VolumeMount{
@@ -67,36 +78,78 @@ func (pm *PodManager) setInitContainer() {
 }
 
 func (pm *PodManager) setContainer(index int, step v1alpha1.Step) corev1.Container {
-	if len(step.Command) == 0 {
+	step = pm.applyVariables(index, step)
+	if len(step.Script) != 0 {
+		step.Command = []string{scriptPath(index, step)}
+	}
+	if step.Approval != nil {
+		// the entrypoint exits as soon as the order reaches an approval step, it only does once approved
+		step.Image = initContainerPath
+	} else if len(step.Command) == 0 {
 		imageInfo, err := pm.getImageInfoWithName(step.Image)
 		if err != nil {
 			return step.Container
 		}
 
-		if imageCmd, ok := imageInfo.Command[osArch]; ok {
-			step.Command = imageCmd.Command
-			if len(step.Args) == 0 {
-				step.Args = imageCmd.Args
-			}
-		} else {
+		imageCmd, ok := imageInfo.Command[image2.OSArch]
+		if !ok {
 			return step.Container // error image command
 		}
-		//step.Command = imageInfo.Command[osArch].Command
+		// the entrypoint runs the command itself, the args are already part of it
+		step.Command, step.Args = imageCmd.Resolve(step.Args), nil
+		if len(step.Command) == 0 {
+			return step.Container
+		}
 	}
 
 	container := corev1.Container{
+		Name:            StepContainerName(index, step),
+		Image:           step.Image,
 		ImagePullPolicy: corev1.PullIfNotPresent,
+		Env:             step.Env,
+		Resources:       step.Resources,
 		Command:         []string{"/entrypoint/bin/entrypoint"},
 		Args: []string{
 			"--wait", "/etc/podinfo/order",
 			"--waitcontent", strconv.Itoa(index + 1),
 			"--out", "stdout",
-			"--command",
 		},
 	}
-	// shc -c
-	container.Args = append(container.Args, strings.Join(step.Command, " "))
-	container.Args = append(container.Args, step.Args...)
+	if step.Approval != nil {
+		container.Args = append(container.Args, "--approval")
+	} else {
+		container.Args = append(container.Args, "--command", step.Command[0])
+	}
+	if step.Timeout != nil {
+		container.Args = append(container.Args, "--timeout", step.Timeout.Duration.String())
+	}
+	// every step may read the results of the earlier ones
+	container.Args = append(container.Args, "--results-root", resultsRootPath, "--step-name", StepName(index, step))
+	if len(step.Results) != 0 {
+		results := make([]string, 0, len(step.Results))
+		for _, result := range step.Results {
+			results = append(results, result.Name)
+		}
+		container.Args = append(container.Args, "--results", strings.Join(results, ","))
+	}
+	if pm.isDAG() {
+		container.Args = append(container.Args, "--ready-set")
+	}
+	if step.OnError == v1alpha1.OnErrorContinue {
+		container.Args = append(container.Args, "--on-error", string(v1alpha1.OnErrorContinue))
+	}
+	if step.Retries > 0 {
+		container.Args = append(container.Args, "--retries", strconv.Itoa(int(step.Retries)))
+		if step.Backoff != nil {
+			container.Args = append(container.Args, "--backoff", step.Backoff.Duration.String())
+		}
+	}
+	// everything after -- is handed to the command untouched, e.g. sh -c "..."
+	if step.Approval == nil {
+		container.Args = append(container.Args, "--")
+		container.Args = append(container.Args, step.Command[1:]...)
+		container.Args = append(container.Args, step.Args...)
+	}
 
 	container.VolumeMounts = []corev1.VolumeMount{
 		{
@@ -107,7 +160,19 @@ func (pm *PodManager) setContainer(index int, step v1alpha1.Step) corev1.Contain
 			Name:      "podinfo",
 			MountPath: "/etc/podinfo",
 		},
+		{
+			Name:      ResultsVolume,
+			MountPath: resultsRootPath,
+		},
+	}
+	if len(step.Script) != 0 {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      DevopsScriptsVolume,
+			MountPath: scriptsRootPath,
+			ReadOnly:  true,
+		})
 	}
+	container.VolumeMounts = append(container.VolumeMounts, pm.workspaceMounts(step)...)
 
 	return container
 }
@@ -121,7 +186,11 @@ func (pm *PodManager) setPodVolumes() {
 			},
 		},
 		{
-			Name: DevopsScriptsVolume,
+			Name:         DevopsScriptsVolume,
+			VolumeSource: pm.scriptsVolumeSource(),
+		},
+		{
+			Name: ResultsVolume,
 			VolumeSource: corev1.VolumeSource{
 				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
@@ -134,7 +203,7 @@ func (pm *PodManager) setPodVolumes() {
 						{
 							Path: "order",
 							FieldRef: &corev1.ObjectFieldSelector{
-								FieldPath: `metadata.annotations['taskorder']`,
+								FieldPath: fmt.Sprintf("metadata.annotations['%s']", annotationsOrderField),
 							},
 						},
 					},
@@ -142,55 +211,161 @@ func (pm *PodManager) setPodVolumes() {
 			},
 		},
 	}
+	pm.pod.Spec.Volumes = append(pm.pod.Spec.Volumes, pm.workspaceVolumes()...)
 }
 
 func (pm *PodManager) setPodMeta() {
-	pm.pod.SetName(generateBaseName(pm.task.GetName()))
+	pm.pod.SetName(pm.baseName)
 	pm.pod.SetNamespace(pm.task.GetNamespace())
 
 	pm.pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+	if pm.task.Spec.ActiveDeadline != nil {
+		// the pod is given the whole deadline, a fraction of a second is rounded up
+		activeDeadlineSeconds := int64(math.Ceil(pm.task.Spec.ActiveDeadline.Duration.Seconds()))
+		pm.pod.Spec.ActiveDeadlineSeconds = &activeDeadlineSeconds
+	}
 
 	annotations := map[string]string{
 		annotationsOrderField: annotationsOrderInitialValue,
+		pm.nameAnnotation():   pm.task.GetName(),
 	}
 	pm.pod.SetAnnotations(annotations)
 }
 
+// setPodTemplate merges the podTemplate of the OrderStep into the pod.
+func (pm *PodManager) setPodTemplate() {
+	if pm.task.Spec.PodTemplate != nil {
+		pm.mergePodTemplate(pm.task.Spec.PodTemplate)
+	}
+}
+
+// mergePodTemplate sets the fields of the pod the template sets.
+func (pm *PodManager) mergePodTemplate(template *v1alpha1.PodTemplate) {
+	template = template.DeepCopy()
+	if template.NodeSelector != nil {
+		pm.pod.Spec.NodeSelector = template.NodeSelector
+	}
+	if template.Tolerations != nil {
+		pm.pod.Spec.Tolerations = template.Tolerations
+	}
+	if template.Affinity != nil {
+		pm.pod.Spec.Affinity = template.Affinity
+	}
+	if len(template.ServiceAccountName) != 0 {
+		pm.pod.Spec.ServiceAccountName = template.ServiceAccountName
+	}
+	if len(template.PriorityClassName) != 0 {
+		pm.pod.Spec.PriorityClassName = template.PriorityClassName
+	}
+	if template.SecurityContext != nil {
+		pm.pod.Spec.SecurityContext = template.SecurityContext
+	}
+	if template.ImagePullSecrets != nil {
+		pm.pod.Spec.ImagePullSecrets = template.ImagePullSecrets
+	}
+	if template.RuntimeClassName != nil {
+		pm.pod.Spec.RuntimeClassName = template.RuntimeClassName
+	}
+}
+
 func (pm *PodManager) Builder(ctx context.Context) error {
-	pod, err := pm.getChildPod(ctx)
+	switch pm.task.Spec.ExecutionMode {
+	case v1alpha1.ExecutionModePodPerStep:
+		return pm.buildStepPods(ctx)
+	case v1alpha1.ExecutionModeJob:
+		return pm.buildJob(ctx)
+	}
+
+	pod, err := pm.GetChildPod(ctx)
 	if err == nil {
-		if pod.Status.Phase == corev1.PodRunning && pod.GetAnnotations()[annotationsOrderField] == annotationsOrderField {
-			pod.GetAnnotations()[annotationsOrderField] = "1"
-			return pm.Client.Update(ctx, pod)
-		} else {
-			if err = pm.forward(ctx, pod); err != nil {
-				return err
-			}
-		}
+		return pm.progress(ctx, pod)
+	}
+	// the pod of a finished task may have been garbage collected, it must not run again
+	if pm.isCancelRequested() || pm.task.Status.CompletionTime != nil {
 		return nil
 	}
 
+	// the claims must exist before the pod, the scheduler waits for them otherwise
+	if err = pm.createDependencies(ctx); err != nil {
+		return err
+	}
+	pm.setTaskPod()
+	pm.pod.OwnerReferences = pm.ownerReferences()
+	_, err = k8s_utils.RetryCreateAndWaitPod(ctx, pm.Client, pm.pod, time.Second, 3)
+	return err
+}
+
+// progress starts the first step once all the containers are running, then moves the order on.
+// Nothing moves while the OrderStep is paused.
+func (pm *PodManager) progress(ctx context.Context, pod *corev1.Pod) error {
+	if pm.isCancelRequested() {
+		return pm.cancel(ctx, pod)
+	}
+	if pm.isPaused() {
+		return nil
+	}
+	if pm.isDAG() {
+		return pm.forwardDAG(ctx, pod)
+	}
+	if pod.Status.Phase == corev1.PodRunning && pod.GetAnnotations()[annotationsOrderField] == annotationsOrderInitialValue {
+		first := pm.nextOrder(1, pod)
+		if pm.held(first) {
+			return nil
+		}
+		pod.GetAnnotations()[annotationsOrderField] = strconv.Itoa(first)
+		return pm.Client.Update(ctx, pod)
+	}
+	return pm.forward(ctx, pod)
+}
+
+// createDependencies creates the objects the pod mounts.
+func (pm *PodManager) createDependencies(ctx context.Context) error {
+	if err := pm.createWorkspaceClaims(ctx); err != nil {
+		return err
+	}
+	return pm.createScriptsConfigMap(ctx)
+}
+
+// setTaskPod builds the pod running all the steps as its containers.
+func (pm *PodManager) setTaskPod() {
 	pm.pod = new(corev1.Pod)
 	pm.setPodMeta()
+	pm.setPodTemplate()
 	pm.setInitContainer()
 
-	containers := make([]corev1.Container, 0, len(pm.task.Spec.Steps))
+	steps := pm.allSteps()
+	containers := make([]corev1.Container, 0, len(steps))
 
-	for i := 0; i < len(pm.task.Spec.Steps); i++ {
-		containers = append(containers, pm.setContainer(i+1, pm.task.Spec.Steps[i]))
+	for i := 0; i < len(steps); i++ {
+		containers = append(containers, pm.setContainer(i, steps[i]))
+	}
+	if pm.sequentialResources() {
+		setSequentialResources(&pm.pod.Spec.InitContainers[0], containers)
 	}
 	pm.pod.Spec.Containers = containers
 	pm.setPodVolumes()
+}
 
-	// set owner
-	pm.pod.OwnerReferences = append(pm.pod.OwnerReferences, metav1.OwnerReference{
-		APIVersion: pm.pod.APIVersion,
-		Kind:       pm.pod.Kind,
-		Name:       pm.pod.Name,
-		UID:        pm.pod.UID,
-	})
-	_, err = k8s_utils.RetryCreateAndWaitPod(ctx, pm.Client, pm.pod, time.Second, 3)
-	return err
+// ownerReferences makes the OrderStep, or the OrderStepRun, the controller of the objects created for it.
+func (pm *PodManager) ownerReferences() []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		{
+			APIVersion:         v1alpha1.OrderTaskApiVersionGroup,
+			Kind:               pm.ownerKind,
+			Name:               pm.task.Name,
+			UID:                pm.task.UID,
+			Controller:         pointer.Bool(true),
+			BlockOwnerDeletion: pointer.Bool(true),
+		},
+	}
+}
+
+// nameAnnotation leads from the pods back to the object they run for.
+func (pm *PodManager) nameAnnotation() string {
+	if pm.ownerKind == v1alpha1.OrderStepRunResourceKind {
+		return v1alpha1.OrderStepRunNameAnnotation
+	}
+	return v1alpha1.OrderStepNameAnnotation
 }
 
 func NewPodManager(task *v1alpha1.OrderStep, client client.Client, cache *lru.Cache) *PodManager {
@@ -198,33 +373,29 @@ func NewPodManager(task *v1alpha1.OrderStep, client client.Client, cache *lru.Ca
 		task:       task,
 		Client:     client,
 		imageCache: cache,
+		ownerKind:  v1alpha1.OrderTaskResourceKind,
+		baseName:   GenerateBaseName(task.GetName()),
 	}
 }
 
+// NewRunPodManager executes the task RunOrderStep built for an OrderStepRun, the objects are owned by the run.
+func NewRunPodManager(task *v1alpha1.OrderStep, client client.Client, cache *lru.Cache) *PodManager {
+	pm := NewPodManager(task, client, cache)
+	pm.ownerKind = v1alpha1.OrderStepRunResourceKind
+	pm.baseName = GenerateRunBaseName(task.GetName())
+	return pm
+}
+
 func (pm *PodManager) getImageInfoWithName(imageName string) (*image2.ImageInfo, error) {
-	ref, err := name.ParseReference(imageName, name.WeakValidation)
-	if err != nil {
-		return nil, err
-	}
-	var imageInfo *image2.ImageInfo
-	if v, ok := pm.imageCache.Get(ref); ok {
-		imageInfo = v.(*image2.ImageInfo)
-	} else {
-		imageInfo, err := image2.ParseImage(imageName)
-		if err != nil {
-			return nil, err
-		}
-		pm.imageCache.Add(ref, imageInfo)
-	}
-	return imageInfo, nil
+	return image2.GetImageInfo(context.TODO(), pm.imageCache, imageName)
 }
 
-func (pm *PodManager) getChildPod(ctx context.Context) (*corev1.Pod, error) {
+func (pm *PodManager) GetChildPod(ctx context.Context) (*corev1.Pod, error) {
 
 	pod := &corev1.Pod{}
 	err := pm.Client.Get(ctx, types.NamespacedName{
 		Namespace: pm.task.Namespace,
-		Name:      orderTaskNamePrefix + pm.task.Name}, pod)
+		Name:      pm.baseName}, pod)
 
 	if err != nil {
 		return nil, err
@@ -243,24 +414,92 @@ func (pm *PodManager) forward(ctx context.Context, pod *corev1.Pod) error {
 	if err != nil {
 		return nil
 	}
-	if order == len(pod.Spec.Containers) {
+	if order < 1 || order >= len(pod.Spec.Containers) {
 		return nil
 	}
 
-	if pod.Status.ContainerStatuses[order-1].State.Terminated == nil {
+	next, ok := pm.followingOrder(order, pod)
+	if !ok {
 		return nil
-	} else {
-		if pod.Status.ContainerStatuses[order-1].State.Terminated.ExitCode != 0 {
-			pod.Annotations[annotationsOrderField] = annotationTaskExistValue
-			return pm.Client.Update(ctx, pod)
-		}
 	}
-	order++
-	pod.Annotations[annotationsOrderField] = strconv.Itoa(order)
+	pod.Annotations[annotationsOrderField] = next
 	return pm.Client.Update(ctx, pod)
 }
 
-func generateBaseName(name string) string {
+// followingOrder returns the order annotation once the step at order finished, ok is false while it runs
+// or while the OrderStep is paused.
+func (pm *PodManager) followingOrder(order int, pod *corev1.Pod) (string, bool) {
+	if pm.isPaused() {
+		return "", false
+	}
+	step := pm.allSteps()[order-1]
+	cs, ok := getContainerStatus(pod, StepContainerName(order-1, step))
+	if !ok || cs.State.Terminated == nil {
+		return "", false
+	}
+	// a best-effort step only leaves its failure in the status, the chain goes on,
+	// so does a failing finally step as the remaining ones must run anyway
+	mainStep := order <= len(pm.task.Spec.Steps)
+	if cs.State.Terminated.ExitCode != 0 && step.OnError != v1alpha1.OnErrorContinue && mainStep {
+		// the finally steps still run, the steps left in between see the order
+		// move past them and exit as skipped
+		if len(pm.task.Spec.Finally) != 0 {
+			return strconv.Itoa(pm.nextOrder(len(pm.task.Spec.Steps)+1, pod)), true
+		}
+		return annotationTaskExistValue, true
+	}
+	// steps whose when expressions do not hold are passed over
+	next := pm.nextOrder(order+1, pod)
+	if pm.held(next) {
+		return "", false
+	}
+	return strconv.Itoa(next), true
+}
+
+// allSteps returns the steps followed by the finally steps, in the order of the pod containers.
+func (pm *PodManager) allSteps() []v1alpha1.Step {
+	steps := make([]v1alpha1.Step, 0, len(pm.task.Spec.Steps)+len(pm.task.Spec.Finally))
+	steps = append(steps, pm.task.Spec.Steps...)
+	return append(steps, pm.task.Spec.Finally...)
+}
+
+func getContainerStatus(pod *corev1.Pod, containerName string) (corev1.ContainerStatus, bool) {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == containerName {
+			return cs, true
+		}
+	}
+	return corev1.ContainerStatus{}, false
+}
+
+// InitContainerName returns the name of the init container copying the entrypoint.
+func InitContainerName(baseName string) string {
+	return baseName + "-init"
+}
+
+// StepName returns the name a step is referred to with, the one of its container when it has none.
+func StepName(index int, step v1alpha1.Step) string {
+	if len(step.Name) == 0 {
+		return StepContainerName(index, step)
+	}
+	return step.Name
+}
+
+// StepContainerName returns the name of the container running the step at index.
+func StepContainerName(index int, step v1alpha1.Step) string {
+	if len(step.Name) == 0 {
+		return "step-" + strconv.Itoa(index+1)
+	}
+	return strings.ToLower(strings.ReplaceAll(step.Name, "_", "-"))
+}
+
+// GenerateBaseName returns the base name of the objects created for an OrderStep, its pod is named so.
+func GenerateBaseName(name string) string {
 	taskName := orderTaskNamePrefix + strings.ReplaceAll(name, "_", "-")
 	return strings.ToLower(taskName)
 }
+
+// GenerateRunBaseName returns the base name of the objects created for an OrderStepRun.
+func GenerateRunBaseName(name string) string {
+	return strings.ToLower(orderRunNamePrefix + strings.ReplaceAll(name, "_", "-"))
+}
@@ -0,0 +1,39 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"reflect"
+	"testing"
+)
+
+func TestMergePodTemplate(t *testing.T) {
+	pm := newTestPodManager(namedStep("compile"))
+	pm.pod = &corev1.Pod{Spec: corev1.PodSpec{
+		ServiceAccountName: "default",
+		NodeSelector:       map[string]string{"kubernetes.io/os": "linux"},
+		PriorityClassName:  "low",
+	}}
+	pm.task.Spec.PodTemplate = &v1alpha1.PodTemplate{
+		NodeSelector:       map[string]string{"kubernetes.io/arch": "amd64"},
+		ServiceAccountName: "builder",
+	}
+	pm.setPodTemplate()
+	// a step overrides the fields it sets only
+	pm.mergePodTemplate(&v1alpha1.PodTemplate{ServiceAccountName: "deployer"})
+
+	if !reflect.DeepEqual(pm.pod.Spec.NodeSelector, map[string]string{"kubernetes.io/arch": "amd64"}) {
+		t.Errorf("expected the nodeSelector of the template, got %v", pm.pod.Spec.NodeSelector)
+	}
+	if pm.pod.Spec.ServiceAccountName != "deployer" {
+		t.Errorf("expected the serviceAccountName of the step, got %s", pm.pod.Spec.ServiceAccountName)
+	}
+	if pm.pod.Spec.PriorityClassName != "low" {
+		t.Errorf("expected the priorityClassName to be left alone, got %s", pm.pod.Spec.PriorityClassName)
+	}
+
+	pm.pod.Spec.NodeSelector["kubernetes.io/arch"] = "arm64"
+	if pm.task.Spec.PodTemplate.NodeSelector["kubernetes.io/arch"] != "amd64" {
+		t.Error("expected the pod not to share the nodeSelector of the template")
+	}
+}
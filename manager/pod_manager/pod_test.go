@@ -0,0 +1,129 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func newTestPodManager(steps ...v1alpha1.Step) *PodManager {
+	return &PodManager{task: &v1alpha1.OrderStep{
+		ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "default"},
+		Spec:       v1alpha1.OrderStepSpec{Steps: steps},
+	}}
+}
+
+func namedStep(name string) v1alpha1.Step {
+	return v1alpha1.Step{Container: corev1.Container{Name: name}}
+}
+
+func continueStep(name string) v1alpha1.Step {
+	step := namedStep(name)
+	step.OnError = v1alpha1.OnErrorContinue
+	return step
+}
+
+func approvalGate(name string) v1alpha1.Step {
+	step := namedStep(name)
+	step.Approval = &v1alpha1.ApprovalGate{}
+	return step
+}
+
+// terminatedPod returns a pod whose step containers exited with the given codes.
+func terminatedPod(exitCodes map[string]int32) *corev1.Pod {
+	pod := &corev1.Pod{}
+	for name, code := range exitCodes {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:  name,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code}},
+		})
+	}
+	return pod
+}
+
+func TestFollowingOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []v1alpha1.Step
+		finally   []v1alpha1.Step
+		order     int
+		exitCodes map[string]int32
+		want      string
+		wantOk    bool
+	}{
+		{
+			name:  "running",
+			steps: []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			order: 1,
+		},
+		{
+			name:      "succeeded",
+			steps:     []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			order:     1,
+			exitCodes: map[string]int32{"compile": 0},
+			want:      "2",
+			wantOk:    true,
+		},
+		{
+			name:      "failed",
+			steps:     []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			order:     1,
+			exitCodes: map[string]int32{"compile": 1},
+			want:      annotationTaskExistValue,
+			wantOk:    true,
+		},
+		{
+			name:      "failed and allowed to",
+			steps:     []v1alpha1.Step{continueStep("lint"), namedStep("test")},
+			order:     1,
+			exitCodes: map[string]int32{"lint": 1},
+			want:      "2",
+			wantOk:    true,
+		},
+		{
+			name:      "failed before the finally steps",
+			steps:     []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			finally:   []v1alpha1.Step{namedStep("cleanup")},
+			order:     1,
+			exitCodes: map[string]int32{"compile": 1},
+			want:      "3",
+			wantOk:    true,
+		},
+		{
+			name:      "last step succeeded before the finally steps",
+			steps:     []v1alpha1.Step{namedStep("compile")},
+			finally:   []v1alpha1.Step{namedStep("cleanup")},
+			order:     1,
+			exitCodes: map[string]int32{"compile": 0},
+			want:      "2",
+			wantOk:    true,
+		},
+		{
+			// the remaining finally steps run anyway
+			name:      "finally step failed",
+			steps:     []v1alpha1.Step{namedStep("compile")},
+			finally:   []v1alpha1.Step{namedStep("cleanup"), namedStep("notify")},
+			order:     2,
+			exitCodes: map[string]int32{"compile": 1, "cleanup": 1},
+			want:      "3",
+			wantOk:    true,
+		},
+		{
+			name:      "held before an approval step",
+			steps:     []v1alpha1.Step{namedStep("build"), approvalGate("release")},
+			order:     1,
+			exitCodes: map[string]int32{"build": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(tt.steps...)
+			pm.task.Spec.Finally = tt.finally
+			got, ok := pm.followingOrder(tt.order, terminatedPod(tt.exitCodes))
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("expected %q/%t, got %q/%t", tt.want, tt.wantOk, got, ok)
+			}
+		})
+	}
+}
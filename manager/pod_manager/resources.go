@@ -0,0 +1,56 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// sequentialResources reports whether the step requests are reduced to what a single step needs,
+// the SequentialResourcesAnnotation set to "false" keeps them as declared. The steps of a graph
+// may run concurrently, their requests are kept too.
+func (pm *PodManager) sequentialResources() bool {
+	return pm.task.GetAnnotations()[v1alpha1.SequentialResourcesAnnotation] != "false" && !pm.isDAG()
+}
+
+// setSequentialResources moves the largest request of every resource onto the init container
+// and zeroes it on the steps. The scheduler sums the requests of the containers while the
+// steps only run one at a time, and reserves the largest of that sum and the init container
+// requests. The limits of the steps are left as declared.
+func setSequentialResources(initContainer *corev1.Container, containers []corev1.Container) {
+	if len(containers) < 2 {
+		return
+	}
+	largest := corev1.ResourceList{}
+	for _, c := range containers {
+		for name, quantity := range effectiveRequests(c) {
+			if current, ok := largest[name]; !ok || quantity.Cmp(current) > 0 {
+				largest[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	if len(largest) == 0 {
+		return
+	}
+	for i := range containers {
+		requests := corev1.ResourceList{}
+		for name := range largest {
+			// an explicit zero, the api server would default a missing request to the limit
+			requests[name] = resource.MustParse("0")
+		}
+		containers[i].Resources.Requests = requests
+	}
+	initContainer.Resources.Requests = largest
+}
+
+// effectiveRequests returns the requests of the container, a limit without request counts as requested.
+func effectiveRequests(c corev1.Container) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for name, quantity := range c.Resources.Limits {
+		requests[name] = quantity
+	}
+	for name, quantity := range c.Resources.Requests {
+		requests[name] = quantity
+	}
+	return requests
+}
@@ -0,0 +1,115 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"testing"
+)
+
+func resourceList(cpu, memory string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	if len(cpu) != 0 {
+		list[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if len(memory) != 0 {
+		list[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+// expectResources fails the test unless got holds the same quantities as want.
+func expectResources(t *testing.T, what string, got, want corev1.ResourceList) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("expected %s %v, got %v", what, want, got)
+		return
+	}
+	for name, quantity := range want {
+		if current, ok := got[name]; !ok || current.Cmp(quantity) != 0 {
+			t.Errorf("expected %s %v, got %v", what, want, got)
+			return
+		}
+	}
+}
+
+func TestSetSequentialResources(t *testing.T) {
+	tests := []struct {
+		name         string
+		containers   []corev1.ResourceRequirements
+		wantInit     corev1.ResourceList
+		wantRequests []corev1.ResourceList
+	}{
+		{
+			name:         "single step",
+			containers:   []corev1.ResourceRequirements{{Requests: resourceList("1", "1Gi")}},
+			wantRequests: []corev1.ResourceList{resourceList("1", "1Gi")},
+		},
+		{
+			name:         "no requests",
+			containers:   []corev1.ResourceRequirements{{}, {}},
+			wantRequests: []corev1.ResourceList{nil, nil},
+		},
+		{
+			name: "largest of every resource",
+			containers: []corev1.ResourceRequirements{
+				{Requests: resourceList("2", "512Mi")},
+				{Requests: resourceList("500m", "2Gi")},
+			},
+			wantInit:     resourceList("2", "2Gi"),
+			wantRequests: []corev1.ResourceList{resourceList("0", "0"), resourceList("0", "0")},
+		},
+		{
+			// the limits are kept, a limit without request is what the step requests
+			name: "limit without request",
+			containers: []corev1.ResourceRequirements{
+				{Requests: resourceList("1", "")},
+				{Limits: resourceList("", "4Gi")},
+			},
+			wantInit:     resourceList("1", "4Gi"),
+			wantRequests: []corev1.ResourceList{resourceList("0", "0"), resourceList("0", "0")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initContainer := &corev1.Container{}
+			containers := make([]corev1.Container, 0, len(tt.containers))
+			for _, resources := range tt.containers {
+				containers = append(containers, corev1.Container{Resources: *resources.DeepCopy()})
+			}
+			setSequentialResources(initContainer, containers)
+
+			expectResources(t, "init container requests", initContainer.Resources.Requests, tt.wantInit)
+			for i, c := range containers {
+				expectResources(t, "step requests", c.Resources.Requests, tt.wantRequests[i])
+				expectResources(t, "step limits", c.Resources.Limits, tt.containers[i].Limits)
+			}
+		})
+	}
+}
+
+func TestSequentialResources(t *testing.T) {
+	compile, test := namedStep("compile"), namedStep("test")
+	test.RunAfter = []string{"compile"}
+	tests := []struct {
+		name       string
+		steps      []v1alpha1.Step
+		annotation string
+		want       bool
+	}{
+		{name: "sequence", steps: []v1alpha1.Step{compile, namedStep("test")}, want: true},
+		{name: "kept as declared", steps: []v1alpha1.Step{compile, namedStep("test")}, annotation: "false"},
+		{name: "graph", steps: []v1alpha1.Step{compile, test}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(tt.steps...)
+			if len(tt.annotation) != 0 {
+				pm.task.Annotations = map[string]string{v1alpha1.SequentialResourcesAnnotation: tt.annotation}
+			}
+			if got := pm.sequentialResources(); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
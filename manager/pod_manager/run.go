@@ -0,0 +1,50 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResolveRunSpec returns the spec the OrderStepRun executes, the spec of its OrderStep with the params
+// bound by the run. It is read from the snapshot in the status of the run once it started.
+func ResolveRunSpec(ctx context.Context, reader client.Reader, run *v1alpha1.OrderStepRun) (*v1alpha1.OrderStepSpec, error) {
+	var spec *v1alpha1.OrderStepSpec
+	if snapshot := run.Status.ResolvedSpec; snapshot != nil {
+		spec = snapshot.DeepCopy()
+	} else {
+		ot := &v1alpha1.OrderStep{}
+		err := reader.Get(ctx, types.NamespacedName{Namespace: run.Namespace, Name: run.Spec.OrderStepRef.Name}, ot)
+		if err != nil {
+			return nil, err
+		}
+		if spec, err = ResolveSpec(ctx, reader, ot); err != nil {
+			return nil, err
+		}
+		if err = bindParams(spec.Params, run.Spec.Params, "OrderStep "+ot.Name, true); err != nil {
+			return nil, err
+		}
+	}
+	spec.Status = run.Spec.Status
+	spec.Approvals = run.Spec.Approvals
+	return spec, nil
+}
+
+// RunOrderStep returns the OrderStep the PodManager executes for the run, it carries the name, uid and
+// status of the run so that the pods, claims and ConfigMaps created are the run's own. NewRunPodManager
+// names them with GenerateRunBaseName so that they never clash with the ones of an OrderStep.
+func RunOrderStep(run *v1alpha1.OrderStepRun, spec *v1alpha1.OrderStepSpec) *v1alpha1.OrderStep {
+	return &v1alpha1.OrderStep{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        run.Name,
+			Namespace:   run.Namespace,
+			UID:         run.UID,
+			Generation:  run.Generation,
+			Annotations: run.Annotations,
+		},
+		Spec:   *spec,
+		Status: *run.Status.DeepCopy(),
+	}
+}
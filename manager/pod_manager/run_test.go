@@ -0,0 +1,70 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveRunSpec(t *testing.T) {
+	definition := newTestPodManager(namedStep("compile")).task
+	definition.Spec.RunPolicy = v1alpha1.RunPolicyManual
+	definition.Spec.Params = []v1alpha1.ParamSpec{{Name: "env"}}
+	reader := &objectReader{orderSteps: map[string]*v1alpha1.OrderStep{"build": definition}}
+
+	run := &v1alpha1.OrderStepRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "build-1", Namespace: "default"},
+		Spec: v1alpha1.OrderStepRunSpec{
+			OrderStepRef: v1alpha1.OrderStepRef{Name: "build"},
+			Params:       []v1alpha1.ParamBinding{{Name: "env", Value: *v1alpha1.NewStringParamValue("prod")}},
+			Status:       v1alpha1.OrderStepSpecStatusPaused,
+		},
+	}
+	spec, err := ResolveRunSpec(context.Background(), reader, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spec.Params[0].Default, v1alpha1.NewStringParamValue("prod")) {
+		t.Errorf("expected the param bound by the run, got %+v", spec.Params[0])
+	}
+	if spec.Status != v1alpha1.OrderStepSpecStatusPaused {
+		t.Errorf("expected the status of the run, got %q", spec.Status)
+	}
+	if definition.Spec.Params[0].Default != nil {
+		t.Error("expected the OrderStep to be left alone")
+	}
+
+	// a run keeps executing what it resolved first
+	run.Status.ResolvedSpec = spec
+	run.Spec.Status = ""
+	definition.Spec.Steps = append(definition.Spec.Steps, namedStep("test"))
+	if spec, err = ResolveRunSpec(context.Background(), reader, run); err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Steps) != 1 || len(spec.Status) != 0 {
+		t.Errorf("expected the snapshot with the status of the run, got %+v", spec)
+	}
+
+	run.Status.ResolvedSpec = nil
+	run.Spec.Params = nil
+	if _, err = ResolveRunSpec(context.Background(), reader, run); err == nil {
+		t.Error("expected an unbound param to be rejected")
+	}
+}
+
+func TestRunOrderStep(t *testing.T) {
+	run := &v1alpha1.OrderStepRun{ObjectMeta: metav1.ObjectMeta{Name: "build-1", Namespace: "default", UID: "1234"}}
+	ot := RunOrderStep(run, &v1alpha1.OrderStepSpec{Steps: []v1alpha1.Step{namedStep("compile")}})
+	if ot.Name != run.Name || ot.UID != run.UID || len(ot.Spec.Steps) != 1 {
+		t.Errorf("expected the OrderStep of the run, got %+v", ot)
+	}
+
+	// the objects of a run never clash with the ones of an OrderStep of the same name
+	runBase, base := GenerateRunBaseName(run.Name), GenerateBaseName(run.Name)
+	if runBase == base || !strings.HasPrefix(runBase, orderRunNamePrefix) {
+		t.Errorf("expected a base name of its own, got %s", runBase)
+	}
+}
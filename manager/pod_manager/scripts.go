@@ -0,0 +1,94 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"path"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+const (
+	scriptsRootPath = "/ordertask/scripts"
+	// defaultShebang runs a script without one, it stops at the first failing command.
+	defaultShebang = "#!/bin/sh\nset -e\n"
+)
+
+// ScriptsConfigMapName returns the name of the ConfigMap holding the scripts of the steps.
+func ScriptsConfigMapName(baseName string) string {
+	return baseName + "-scripts"
+}
+
+func scriptPath(index int, step v1alpha1.Step) string {
+	return path.Join(scriptsRootPath, StepContainerName(index, step))
+}
+
+// scriptContent returns the file the script is written to, prefixed with the default shebang when it has none.
+func scriptContent(script string) string {
+	if strings.HasPrefix(script, "#!") {
+		return script
+	}
+	return defaultShebang + script
+}
+
+func (pm *PodManager) hasScripts() bool {
+	for _, step := range pm.allSteps() {
+		if len(step.Script) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptsVolumeSource returns the ConfigMap the scripts are read from, an emptyDir when no step has one.
+func (pm *PodManager) scriptsVolumeSource() corev1.VolumeSource {
+	if !pm.hasScripts() {
+		return corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
+	}
+	return corev1.VolumeSource{
+		ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: ScriptsConfigMapName(pm.baseName)},
+			DefaultMode:          pointer.Int32(0755),
+		},
+	}
+}
+
+// createScriptsConfigMap writes the scripts of the steps, after substituting their variables,
+// into a ConfigMap owned by the OrderStep and keyed by container name.
+func (pm *PodManager) createScriptsConfigMap(ctx context.Context) error {
+	if !pm.hasScripts() {
+		return nil
+	}
+	data := make(map[string]string)
+	for i, step := range pm.allSteps() {
+		if len(step.Script) == 0 {
+			continue
+		}
+		data[StepContainerName(i, step)] = scriptContent(pm.applyVariables(i, step).Script)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ScriptsConfigMapName(pm.baseName),
+			Namespace:       pm.task.GetNamespace(),
+			OwnerReferences: pm.ownerReferences(),
+		},
+		Data: data,
+	}
+	err := pm.Client.Create(ctx, cm)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	// left over by a pod that has been deleted, the spec may have changed since
+	existing := &corev1.ConfigMap{}
+	if err = pm.Client.Get(ctx, client.ObjectKeyFromObject(cm), existing); err != nil {
+		return err
+	}
+	existing.Data = data
+	return pm.Client.Update(ctx, existing)
+}
@@ -0,0 +1,41 @@
+package pod_manager
+
+import (
+	"testing"
+)
+
+func TestScriptContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{name: "no shebang", script: "go build ./...\n", want: defaultShebang + "go build ./...\n"},
+		{name: "shebang kept", script: "#!/usr/bin/env python3\nprint(1)\n", want: "#!/usr/bin/env python3\nprint(1)\n"},
+		{name: "empty", want: defaultShebang},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scriptContent(tt.script); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScriptsVolumeSource(t *testing.T) {
+	script := namedStep("compile")
+	script.Script = "go build ./..."
+	pm := newTestPodManager(namedStep("checkout"))
+	pm.baseName = GenerateBaseName(pm.task.Name)
+	if source := pm.scriptsVolumeSource(); source.EmptyDir == nil {
+		t.Errorf("expected an emptyDir without scripts, got %+v", source)
+	}
+
+	// a finally step needs the ConfigMap too
+	pm.task.Spec.Finally = append(pm.task.Spec.Finally, script)
+	source := pm.scriptsVolumeSource()
+	if source.ConfigMap == nil || source.ConfigMap.Name != ScriptsConfigMapName(pm.baseName) {
+		t.Errorf("expected the scripts ConfigMap, got %+v", source)
+	}
+}
@@ -0,0 +1,347 @@
+package pod_manager
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/termination"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strconv"
+)
+
+// podState is what the status of every step is computed from.
+type podState struct {
+	pod               *corev1.Pod
+	order             int
+	containerStatuses map[string]corev1.ContainerStatus
+	previous          map[string]v1alpha1.StepStatus
+	now               metav1.Time
+
+	// readySet is the order annotation parsed when the steps form a graph
+	readySet *readySet
+}
+
+// ComputeStatus derives the OrderStep status from the child pod, pod is nil
+// when it has not been created yet.
+func (pm *PodManager) ComputeStatus(pod *corev1.Pod) v1alpha1.OrderStepStatus {
+	status := *pm.task.Status.DeepCopy()
+	status.ObservedGeneration = pm.task.Generation
+
+	state := &podState{
+		pod:               pod,
+		containerStatuses: make(map[string]corev1.ContainerStatus),
+		previous:          make(map[string]v1alpha1.StepStatus, len(status.Steps)+len(status.Finally)),
+		now:               metav1.Now(),
+	}
+	for _, s := range append(status.Steps, status.Finally...) {
+		state.previous[s.Container] = s
+	}
+	if pod != nil {
+		for _, cs := range pod.Status.ContainerStatuses {
+			state.containerStatuses[cs.Name] = cs
+		}
+		state.order, _ = strconv.Atoi(pod.Annotations[annotationsOrderField])
+		if pm.isDAG() {
+			set := parseReadySet(pod.Annotations[annotationsOrderField])
+			state.readySet = &set
+		}
+	}
+
+	status.CurrentStep = ""
+	status.Steps = make([]v1alpha1.StepStatus, 0, len(pm.task.Spec.Steps))
+	status.Finally = nil
+	for i, step := range pm.allSteps() {
+		stepStatus := computeStepStatus(i, step, state)
+		if stepStatus.Reason == v1alpha1.StepReasonRunning {
+			// independent steps of a graph run at the same time
+			if len(status.CurrentStep) != 0 {
+				status.CurrentStep += ","
+			}
+			status.CurrentStep += stepStatus.Name
+		}
+		if i < len(pm.task.Spec.Steps) {
+			status.Steps = append(status.Steps, stepStatus)
+		} else {
+			status.Finally = append(status.Finally, stepStatus)
+		}
+	}
+	for i, step := range pm.task.Spec.Steps {
+		if approval, ok := pm.approval(status.Steps[i].Name); ok && step.Approval != nil {
+			status.Steps[i].ApprovedBy = approval.ApprovedBy
+			status.Steps[i].ApprovedAt = approval.ApprovedAt.DeepCopy()
+		}
+	}
+	// approval steps are only ever main steps
+	awaiting := pm.awaitingApproval(state, append(append([]v1alpha1.StepStatus{}, status.Steps...), status.Finally...))
+	for _, i := range awaiting {
+		status.Steps[i].Reason = v1alpha1.StepReasonAwaitingApproval
+	}
+	allStatuses := append(append([]v1alpha1.StepStatus{}, status.Steps...), status.Finally...)
+
+	status.Phase = podPhaseToOrderStepPhase(pod)
+	// the pod fails as soon as one container exits non-zero, even when the step is allowed to
+	if status.Phase == v1alpha1.OrderStepFailed && pm.onlyIgnoredFailures(pod, allStatuses) {
+		status.Phase = v1alpha1.OrderStepSucceeded
+	}
+	switch {
+	case pm.isCancelRequested() && (pod == nil || isTaskCancelled(pod)):
+		status.Phase = v1alpha1.OrderStepCancelled
+		cancelSteps(status.Steps, state.now)
+		cancelSteps(status.Finally, state.now)
+		allStatuses = append(append([]v1alpha1.StepStatus{}, status.Steps...), status.Finally...)
+		status.CurrentStep = ""
+	case pm.isPaused() && (status.Phase == v1alpha1.OrderStepPending || status.Phase == v1alpha1.OrderStepRunning):
+		status.Phase = v1alpha1.OrderStepPaused
+	}
+	if pod != nil && pod.Status.StartTime != nil && status.StartTime == nil {
+		status.StartTime = pod.Status.StartTime.DeepCopy()
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.ConditionSucceeded,
+		Status:             metav1.ConditionUnknown,
+		Reason:             string(status.Phase),
+		ObservedGeneration: pm.task.Generation,
+	}
+	switch status.Phase {
+	case v1alpha1.OrderStepSucceeded:
+		condition.Status = metav1.ConditionTrue
+		condition.Message = fmt.Sprintf("all %d steps completed", len(allStatuses))
+		if ignored := countIgnoredFailures(allStatuses); ignored > 0 {
+			condition.Message = fmt.Sprintf("%s, %d failed steps were allowed to fail", condition.Message, ignored)
+		}
+	case v1alpha1.OrderStepCancelled:
+		condition.Status = metav1.ConditionFalse
+		condition.Message = "cancelled through spec.status"
+	case v1alpha1.OrderStepFailed:
+		condition.Status = metav1.ConditionFalse
+		if isPodDeadlineExceeded(pod) {
+			condition.Reason = v1alpha1.StepReasonTimedOut
+			condition.Message = fmt.Sprintf("activeDeadline %s exceeded", pm.task.Spec.ActiveDeadline.Duration)
+			break
+		}
+		steps := pm.allSteps()
+		for i, s := range allStatuses {
+			if steps[i].OnError == v1alpha1.OnErrorContinue {
+				continue
+			}
+			if s.Reason == v1alpha1.StepReasonFailed {
+				condition.Message = fmt.Sprintf("step %s exited with code %d", s.Name, *s.ExitCode)
+				break
+			}
+			if s.Reason == v1alpha1.StepReasonTimedOut {
+				condition.Reason = v1alpha1.StepReasonTimedOut
+				condition.Message = fmt.Sprintf("step %s exceeded its timeout", s.Name)
+				break
+			}
+		}
+	case v1alpha1.OrderStepRunning:
+		condition.Message = fmt.Sprintf("running step %s", status.CurrentStep)
+		if len(status.CurrentStep) == 0 && len(awaiting) != 0 {
+			condition.Message = approvalMessage(pm.allSteps(), allStatuses, awaiting)
+		}
+	case v1alpha1.OrderStepPaused:
+		condition.Message = "paused, no further step starts until spec.status is cleared"
+	}
+	if condition.Status != metav1.ConditionUnknown && status.CompletionTime == nil {
+		status.CompletionTime = &state.now
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+
+	if len(awaiting) != 0 && condition.Status == metav1.ConditionUnknown {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionAwaitingApproval,
+			Status:             metav1.ConditionTrue,
+			Reason:             v1alpha1.StepReasonAwaitingApproval,
+			Message:            approvalMessage(pm.allSteps(), allStatuses, awaiting),
+			ObservedGeneration: pm.task.Generation,
+		})
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, v1alpha1.ConditionAwaitingApproval)
+	}
+	return status
+}
+
+// computeStepStatus derives the status of the step run by the container at index.
+func computeStepStatus(index int, step v1alpha1.Step, state *podState) v1alpha1.StepStatus {
+	containerName := StepContainerName(index, step)
+	stepStatus := state.previous[containerName]
+	stepStatus.Container = containerName
+	stepStatus.Name = StepName(index, step)
+
+	cs, ok := state.containerStatuses[containerName]
+	switch {
+	case isPodDeadlineExceeded(state.pod) && state.isCurrent(index, cs, ok):
+		// the kubelet killed the pod in the middle of this step
+		if ok && cs.State.Terminated != nil {
+			stepStatus.FinishedAt = cs.State.Terminated.FinishedAt.DeepCopy()
+			stepStatus.ExitCode = &cs.State.Terminated.ExitCode
+		} else if stepStatus.FinishedAt == nil {
+			stepStatus.FinishedAt = state.now.DeepCopy()
+		}
+		stepStatus.Reason = v1alpha1.StepReasonTimedOut
+	case ok && cs.State.Terminated != nil:
+		terminated := cs.State.Terminated
+		stepStatus.FinishedAt = terminated.FinishedAt.DeepCopy()
+		stepStatus.ExitCode = &terminated.ExitCode
+		stepStatus.Reason = v1alpha1.StepReasonCompleted
+		if terminated.ExitCode != 0 {
+			stepStatus.Reason = v1alpha1.StepReasonFailed
+		}
+		// the entrypoint leaves its attempts in the termination log
+		if msg := termination.Parse(terminated.Message); msg != nil {
+			setStepAttempts(&stepStatus, msg)
+		}
+		if stepStatus.StartedAt == nil && stepStatus.Reason != v1alpha1.StepReasonSkipped &&
+			(stepStatus.Reason != v1alpha1.StepReasonCancelled || len(stepStatus.Attempts) != 0) {
+			stepStatus.StartedAt = terminated.StartedAt.DeepCopy()
+		}
+	case ok && cs.State.Running != nil && state.isCurrent(index, cs, ok):
+		// every container is running while its entrypoint waits, only the
+		// one matching the order annotation is actually executing
+		if stepStatus.StartedAt == nil {
+			stepStatus.StartedAt = state.now.DeepCopy()
+		}
+		stepStatus.Reason = v1alpha1.StepReasonRunning
+	case !ok && state.order > index+1:
+		// the pod of a skipped step is never created in podPerStep mode
+		if stepStatus.FinishedAt == nil {
+			stepStatus.FinishedAt = state.now.DeepCopy()
+		}
+		stepStatus.Reason = v1alpha1.StepReasonSkipped
+	default:
+		stepStatus.Reason = v1alpha1.StepReasonWaiting
+	}
+	return stepStatus
+}
+
+// cancelSteps marks the steps that did not finish as cancelled, the one being terminated included.
+func cancelSteps(steps []v1alpha1.StepStatus, now metav1.Time) {
+	for i := range steps {
+		switch steps[i].Reason {
+		case v1alpha1.StepReasonWaiting, v1alpha1.StepReasonRunning, v1alpha1.StepReasonAwaitingApproval:
+		default:
+			continue
+		}
+		steps[i].Reason = v1alpha1.StepReasonCancelled
+		if steps[i].FinishedAt == nil {
+			steps[i].FinishedAt = now.DeepCopy()
+		}
+	}
+}
+
+// isCurrent reports whether the step at index is the one being executed, or one of them when
+// the steps form a graph. A released step that wrote its termination log finished on its own.
+func (state *podState) isCurrent(index int, cs corev1.ContainerStatus, ok bool) bool {
+	if state.readySet == nil {
+		return state.order == index+1
+	}
+	if !state.readySet.run[index+1] {
+		return false
+	}
+	return !ok || cs.State.Terminated == nil || termination.Parse(cs.State.Terminated.Message) == nil
+}
+
+// onlyIgnoredFailures reports whether every step ran and all failed ones set onError to continue.
+func (pm *PodManager) onlyIgnoredFailures(pod *corev1.Pod, steps []v1alpha1.StepStatus) bool {
+	if pod == nil || isTaskAborted(pod) || isPodDeadlineExceeded(pod) {
+		return false
+	}
+	allSteps := pm.allSteps()
+	for i, s := range steps {
+		if s.FinishedAt == nil {
+			return false
+		}
+		if isStepFailed(s.Reason) && allSteps[i].OnError != v1alpha1.OnErrorContinue {
+			return false
+		}
+	}
+	return true
+}
+
+func isStepFailed(reason string) bool {
+	return reason == v1alpha1.StepReasonFailed || reason == v1alpha1.StepReasonTimedOut
+}
+
+func countIgnoredFailures(steps []v1alpha1.StepStatus) int {
+	count := 0
+	for _, s := range steps {
+		if isStepFailed(s.Reason) {
+			count++
+		}
+	}
+	return count
+}
+
+func setStepAttempts(stepStatus *v1alpha1.StepStatus, msg *termination.Message) {
+	// TimedOut, Skipped or Cancelled
+	if len(msg.Reason) != 0 {
+		stepStatus.Reason = msg.Reason
+	}
+	stepStatus.Results = nil
+	for _, name := range sortedKeys(msg.Results) {
+		stepStatus.Results = append(stepStatus.Results, v1alpha1.StepResultValue{Name: name, Value: msg.Results[name]})
+	}
+	if len(msg.Attempts) == 0 {
+		return
+	}
+	// the first attempt tells when the step really started, the container started long before
+	stepStatus.StartedAt = &metav1.Time{Time: msg.Attempts[0].StartedAt}
+	// the container of an onError: continue step exits 0 even though the step failed
+	exitCode := msg.Attempts[len(msg.Attempts)-1].ExitCode
+	stepStatus.ExitCode = &exitCode
+	stepStatus.Attempts = make([]v1alpha1.StepAttempt, 0, len(msg.Attempts))
+	for _, attempt := range msg.Attempts {
+		stepAttempt := v1alpha1.StepAttempt{
+			StartedAt: metav1.Time{Time: attempt.StartedAt},
+			Duration:  metav1.Duration{Duration: attempt.Duration},
+			ExitCode:  attempt.ExitCode,
+		}
+		if attempt.TimedOut {
+			stepAttempt.Reason = v1alpha1.StepReasonTimedOut
+		}
+		if attempt.Cancelled {
+			stepAttempt.Reason = v1alpha1.StepReasonCancelled
+		}
+		stepStatus.Attempts = append(stepStatus.Attempts, stepAttempt)
+	}
+}
+
+const (
+	// podReasonDeadlineExceeded is the pod status reason set by the kubelet once activeDeadlineSeconds passed
+	podReasonDeadlineExceeded = "DeadlineExceeded"
+)
+
+func podPhaseToOrderStepPhase(pod *corev1.Pod) v1alpha1.OrderStepPhase {
+	if pod == nil {
+		return v1alpha1.OrderStepPending
+	}
+	if isTaskAborted(pod) {
+		return v1alpha1.OrderStepFailed
+	}
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return v1alpha1.OrderStepSucceeded
+	case corev1.PodFailed:
+		return v1alpha1.OrderStepFailed
+	case corev1.PodRunning:
+		return v1alpha1.OrderStepRunning
+	default:
+		return v1alpha1.OrderStepPending
+	}
+}
+
+func isPodDeadlineExceeded(pod *corev1.Pod) bool {
+	return pod != nil && pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == podReasonDeadlineExceeded
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
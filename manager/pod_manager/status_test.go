@@ -0,0 +1,260 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// taskPod returns the pod at the given order whose step containers are in the given states.
+func taskPod(phase corev1.PodPhase, order string, states map[string]corev1.ContainerState) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotationsOrderField: order}},
+		Status:     corev1.PodStatus{Phase: phase, StartTime: &metav1.Time{}},
+	}
+	for name, state := range states {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{Name: name, State: state})
+	}
+	return pod
+}
+
+func running() corev1.ContainerState {
+	return corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+}
+
+// exited returns the state of a container whose entrypoint left message in the termination log.
+func exited(code int32, message string) corev1.ContainerState {
+	return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code, Message: message}}
+}
+
+func TestComputeStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		steps          []v1alpha1.Step
+		specStatus     v1alpha1.OrderStepSpecStatus
+		activeDeadline *metav1.Duration
+		pod            *corev1.Pod
+
+		wantPhase     v1alpha1.OrderStepPhase
+		wantCondition metav1.ConditionStatus
+		// wantMessage is contained in the message of the Succeeded condition
+		wantMessage  string
+		wantCurrent  string
+		wantReasons  []string
+		wantExitCode map[string]int32
+		wantAttempts map[string]int
+		wantStarted  bool
+		wantFinished bool
+	}{
+		{
+			name:          "pod not created",
+			steps:         []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			wantPhase:     v1alpha1.OrderStepPending,
+			wantCondition: metav1.ConditionUnknown,
+			wantReasons:   []string{v1alpha1.StepReasonWaiting, v1alpha1.StepReasonWaiting},
+		},
+		{
+			name:          "running",
+			steps:         []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			pod:           taskPod(corev1.PodRunning, "1", map[string]corev1.ContainerState{"compile": running(), "test": running()}),
+			wantPhase:     v1alpha1.OrderStepRunning,
+			wantCondition: metav1.ConditionUnknown,
+			wantMessage:   "running step compile",
+			wantCurrent:   "compile",
+			wantReasons:   []string{v1alpha1.StepReasonRunning, v1alpha1.StepReasonWaiting},
+			wantStarted:   true,
+		},
+		{
+			name:          "succeeded",
+			steps:         []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			pod:           taskPod(corev1.PodSucceeded, "2", map[string]corev1.ContainerState{"compile": exited(0, ""), "test": exited(0, "")}),
+			wantPhase:     v1alpha1.OrderStepSucceeded,
+			wantCondition: metav1.ConditionTrue,
+			wantMessage:   "all 2 steps completed",
+			wantReasons:   []string{v1alpha1.StepReasonCompleted, v1alpha1.StepReasonCompleted},
+			wantExitCode:  map[string]int32{"compile": 0, "test": 0},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			name:  "failed",
+			steps: []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			pod: taskPod(corev1.PodFailed, annotationTaskExistValue, map[string]corev1.ContainerState{
+				"compile": exited(2, ""),
+				"test":    exited(0, `{"reason":"Skipped"}`),
+			}),
+			wantPhase:     v1alpha1.OrderStepFailed,
+			wantCondition: metav1.ConditionFalse,
+			wantMessage:   "step compile exited with code 2",
+			wantReasons:   []string{v1alpha1.StepReasonFailed, v1alpha1.StepReasonSkipped},
+			wantExitCode:  map[string]int32{"compile": 2},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			name:  "step timed out",
+			steps: []v1alpha1.Step{namedStep("compile")},
+			pod: taskPod(corev1.PodFailed, annotationTaskExistValue, map[string]corev1.ContainerState{
+				"compile": exited(124, `{"reason":"TimedOut","attempts":[{"exitCode":124,"timedOut":true}]}`),
+			}),
+			wantPhase:     v1alpha1.OrderStepFailed,
+			wantCondition: metav1.ConditionFalse,
+			wantMessage:   "step compile exceeded its timeout",
+			wantReasons:   []string{v1alpha1.StepReasonTimedOut},
+			wantExitCode:  map[string]int32{"compile": 124},
+			wantAttempts:  map[string]int{"compile": 1},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			// the kubelet killed the pod in the middle of the step
+			name:           "activeDeadline exceeded",
+			steps:          []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			activeDeadline: &metav1.Duration{Duration: time.Minute},
+			pod: func() *corev1.Pod {
+				pod := taskPod(corev1.PodFailed, "1", map[string]corev1.ContainerState{"compile": exited(137, ""), "test": running()})
+				pod.Status.Reason = podReasonDeadlineExceeded
+				return pod
+			}(),
+			wantPhase:     v1alpha1.OrderStepFailed,
+			wantCondition: metav1.ConditionFalse,
+			wantMessage:   "activeDeadline 1m0s exceeded",
+			wantReasons:   []string{v1alpha1.StepReasonTimedOut, v1alpha1.StepReasonWaiting},
+			wantExitCode:  map[string]int32{"compile": 137},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			name:  "retried",
+			steps: []v1alpha1.Step{namedStep("compile")},
+			pod: taskPod(corev1.PodSucceeded, "1", map[string]corev1.ContainerState{
+				"compile": exited(0, `{"attempts":[{"exitCode":1},{"exitCode":1},{"exitCode":0}]}`),
+			}),
+			wantPhase:     v1alpha1.OrderStepSucceeded,
+			wantCondition: metav1.ConditionTrue,
+			wantReasons:   []string{v1alpha1.StepReasonCompleted},
+			wantExitCode:  map[string]int32{"compile": 0},
+			wantAttempts:  map[string]int{"compile": 3},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			name: "skipped by its when expressions",
+			steps: []v1alpha1.Step{namedStep("compile"), whenStep("deploy", v1alpha1.WhenExpression{
+				Input: "$(params.env)", Operator: v1alpha1.WhenOperatorIn, Values: []string{"prod"},
+			})},
+			pod: taskPod(corev1.PodSucceeded, "2", map[string]corev1.ContainerState{
+				"compile": exited(0, ""),
+				"deploy":  exited(0, `{"reason":"Skipped"}`),
+			}),
+			wantPhase:     v1alpha1.OrderStepSucceeded,
+			wantCondition: metav1.ConditionTrue,
+			wantReasons:   []string{v1alpha1.StepReasonCompleted, v1alpha1.StepReasonSkipped},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			// the pod fails with the container of the step allowed to fail
+			name:          "only ignored failures",
+			steps:         []v1alpha1.Step{continueStep("lint"), namedStep("test")},
+			pod:           taskPod(corev1.PodFailed, "2", map[string]corev1.ContainerState{"lint": exited(1, ""), "test": exited(0, "")}),
+			wantPhase:     v1alpha1.OrderStepSucceeded,
+			wantCondition: metav1.ConditionTrue,
+			wantMessage:   "1 failed steps were allowed to fail",
+			wantReasons:   []string{v1alpha1.StepReasonFailed, v1alpha1.StepReasonCompleted},
+			wantExitCode:  map[string]int32{"lint": 1, "test": 0},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			name:       "cancelled",
+			steps:      []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			specStatus: v1alpha1.OrderStepSpecStatusCancelled,
+			pod: taskPod(corev1.PodRunning, annotationTaskCancelledValue, map[string]corev1.ContainerState{
+				"compile": exited(130, `{"reason":"Cancelled","attempts":[{"exitCode":130,"cancelled":true}]}`),
+				"test":    running(),
+			}),
+			wantPhase:     v1alpha1.OrderStepCancelled,
+			wantCondition: metav1.ConditionFalse,
+			wantMessage:   "cancelled through spec.status",
+			wantReasons:   []string{v1alpha1.StepReasonCancelled, v1alpha1.StepReasonCancelled},
+			wantExitCode:  map[string]int32{"compile": 130},
+			wantAttempts:  map[string]int{"compile": 1},
+			wantStarted:   true,
+			wantFinished:  true,
+		},
+		{
+			name:          "cancelled before the pod was created",
+			steps:         []v1alpha1.Step{namedStep("compile")},
+			specStatus:    v1alpha1.OrderStepSpecStatusCancelled,
+			wantPhase:     v1alpha1.OrderStepCancelled,
+			wantCondition: metav1.ConditionFalse,
+			wantReasons:   []string{v1alpha1.StepReasonCancelled},
+			wantFinished:  true,
+		},
+		{
+			name:          "paused",
+			steps:         []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			specStatus:    v1alpha1.OrderStepSpecStatusPaused,
+			pod:           taskPod(corev1.PodRunning, "2", map[string]corev1.ContainerState{"compile": exited(0, ""), "test": running()}),
+			wantPhase:     v1alpha1.OrderStepPaused,
+			wantCondition: metav1.ConditionUnknown,
+			wantMessage:   "paused",
+			wantCurrent:   "test",
+			wantReasons:   []string{v1alpha1.StepReasonCompleted, v1alpha1.StepReasonRunning},
+			wantExitCode:  map[string]int32{"compile": 0},
+			wantStarted:   true,
+		},
+		{
+			name:          "awaiting approval",
+			steps:         []v1alpha1.Step{namedStep("compile"), approvalGate("release")},
+			pod:           taskPod(corev1.PodRunning, "1", map[string]corev1.ContainerState{"compile": exited(0, ""), "release": running()}),
+			wantPhase:     v1alpha1.OrderStepRunning,
+			wantCondition: metav1.ConditionUnknown,
+			wantMessage:   "step release awaits approval",
+			wantReasons:   []string{v1alpha1.StepReasonCompleted, v1alpha1.StepReasonAwaitingApproval},
+			wantExitCode:  map[string]int32{"compile": 0},
+			wantStarted:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(tt.steps...)
+			pm.task.Spec.Status = tt.specStatus
+			pm.task.Spec.ActiveDeadline = tt.activeDeadline
+			status := pm.ComputeStatus(tt.pod)
+
+			if status.Phase != tt.wantPhase {
+				t.Errorf("expected phase %s, got %s", tt.wantPhase, status.Phase)
+			}
+			condition := meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionSucceeded)
+			if condition == nil || condition.Status != tt.wantCondition || !strings.Contains(condition.Message, tt.wantMessage) {
+				t.Errorf("expected a %s condition with %q, got %+v", tt.wantCondition, tt.wantMessage, condition)
+			}
+			if status.CurrentStep != tt.wantCurrent {
+				t.Errorf("expected current step %q, got %q", tt.wantCurrent, status.CurrentStep)
+			}
+			if (status.StartTime != nil) != tt.wantStarted || (status.CompletionTime != nil) != tt.wantFinished {
+				t.Errorf("expected started/finished %t/%t, got %v/%v", tt.wantStarted, tt.wantFinished, status.StartTime, status.CompletionTime)
+			}
+			var reasons []string
+			for _, s := range status.Steps {
+				reasons = append(reasons, s.Reason)
+				if code, ok := tt.wantExitCode[s.Name]; ok && (s.ExitCode == nil || *s.ExitCode != code) {
+					t.Errorf("expected step %s to exit with %d, got %v", s.Name, code, s.ExitCode)
+				}
+				if len(s.Attempts) != tt.wantAttempts[s.Name] {
+					t.Errorf("expected %d attempts of step %s, got %d", tt.wantAttempts[s.Name], s.Name, len(s.Attempts))
+				}
+			}
+			if !reflect.DeepEqual(reasons, tt.wantReasons) {
+				t.Errorf("expected step reasons %v, got %v", tt.wantReasons, reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/k8s_utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// annotationsNextOrderField is set on the pod of a finished step in podPerStep mode, it holds
+	// the order of the step run next, one past the last step once done, or -1 when the task aborted.
+	annotationsNextOrderField = "nextOrderField"
+)
+
+// StepPodName returns the name of the pod running the step at index in podPerStep mode.
+func StepPodName(baseName string, index int, step v1alpha1.Step) string {
+	return baseName + "-" + StepContainerName(index, step)
+}
+
+// GetTaskPod returns the pod the status is computed from. In podPerStep mode it is a view merging
+// the step pods, it is nil until the first of them has been created. In job mode it is the
+// latest pod of the Job with the phase of the Job.
+func (pm *PodManager) GetTaskPod(ctx context.Context) (*corev1.Pod, error) {
+	switch pm.task.Spec.ExecutionMode {
+	case v1alpha1.ExecutionModePodPerStep:
+	case v1alpha1.ExecutionModeJob:
+		return pm.jobPodView(ctx)
+	default:
+		return pm.GetChildPod(ctx)
+	}
+	pods, err := pm.getStepPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pm.stepPodsView(pods), nil
+}
+
+// buildStepPods creates the pod of the first step, then the pod of the next one every time a step pod finished.
+func (pm *PodManager) buildStepPods(ctx context.Context) error {
+	pods, err := pm.getStepPods(ctx)
+	if err != nil {
+		return err
+	}
+	view := pm.stepPodsView(pods)
+	if view == nil {
+		first := pm.nextOrder(1, nil)
+		// the step pods of a finished task may have been garbage collected
+		if pm.isCancelRequested() || pm.held(first) || pm.task.Status.CompletionTime != nil {
+			return nil
+		}
+		return pm.createStepPod(ctx, first-1, nil)
+	}
+
+	latest := latestStepPod(pods)
+	if latest < 0 {
+		return nil
+	}
+	last := pods[latest]
+	if pm.isCancelRequested() {
+		return pm.cancel(ctx, last)
+	}
+	if pm.isPaused() {
+		return nil
+	}
+	if next, ok := last.Annotations[annotationsNextOrderField]; ok {
+		// the pod of the next step may have failed to be created
+		order, _ := strconv.Atoi(next)
+		if order > latest+1 && order <= len(pods) && pods[order-1] == nil {
+			return pm.createStepPod(ctx, order-1, view)
+		}
+		return nil
+	}
+
+	if isPodDeadlineExceeded(last) {
+		last.Annotations[annotationsNextOrderField] = annotationTaskExistValue
+	} else if next, ok := pm.followingOrder(latest+1, view); ok {
+		last.Annotations[annotationsNextOrderField] = next
+	} else {
+		return nil
+	}
+	if err = pm.Client.Update(ctx, last); err != nil {
+		return err
+	}
+	order, _ := strconv.Atoi(last.Annotations[annotationsNextOrderField])
+	if order < 1 || order > len(pods) {
+		return nil
+	}
+	// the results of the finished steps are known, the update above is part of the view
+	return pm.createStepPod(ctx, order-1, pm.stepPodsView(pods))
+}
+
+// createStepPod creates the pod running only the step at index, its order annotation is already the
+// one its entrypoint waits for.
+func (pm *PodManager) createStepPod(ctx context.Context, index int, view *corev1.Pod) error {
+	steps := pm.allSteps()
+	if index < 0 || index >= len(steps) {
+		return nil
+	}
+	step := steps[index]
+	if err := pm.createDependencies(ctx); err != nil {
+		return err
+	}
+
+	pm.stepVariables = pm.variables(view)
+	pm.pod = new(corev1.Pod)
+	pm.setPodMeta()
+	pm.pod.SetName(StepPodName(pm.baseName, index, step))
+	pm.pod.Annotations[annotationsOrderField] = strconv.Itoa(index + 1)
+	pm.setStepPodDeadline()
+	pm.setPodTemplate()
+	if step.PodTemplate != nil {
+		pm.mergePodTemplate(step.PodTemplate)
+	}
+	pm.setInitContainer()
+	pm.pod.Spec.Containers = []corev1.Container{pm.setContainer(index, step)}
+	pm.setPodVolumes()
+	pm.pod.OwnerReferences = pm.ownerReferences()
+
+	// a short step may be over before the pod could be seen running, its update events drive the chain
+	err := pm.Client.Create(ctx, pm.pod)
+	if k8s_utils.IsKubernetesResourceAlreadyExistError(err) {
+		return nil
+	}
+	return err
+}
+
+// setStepPodDeadline bounds the step pod by what is left of the activeDeadline of the OrderStep.
+func (pm *PodManager) setStepPodDeadline() {
+	if pm.task.Spec.ActiveDeadline == nil || pm.task.Status.StartTime == nil {
+		return
+	}
+	left := pm.task.Spec.ActiveDeadline.Duration - time.Since(pm.task.Status.StartTime.Time)
+	activeDeadlineSeconds := int64(math.Ceil(left.Seconds()))
+	if activeDeadlineSeconds < 1 {
+		activeDeadlineSeconds = 1
+	}
+	pm.pod.Spec.ActiveDeadlineSeconds = &activeDeadlineSeconds
+}
+
+// getStepPods returns the pod of every step by index, nil for the ones not created.
+func (pm *PodManager) getStepPods(ctx context.Context) ([]*corev1.Pod, error) {
+	steps := pm.allSteps()
+	pods := make([]*corev1.Pod, len(steps))
+	for i, step := range steps {
+		pod := &corev1.Pod{}
+		err := pm.Client.Get(ctx, types.NamespacedName{
+			Namespace: pm.task.Namespace,
+			Name:      StepPodName(pm.baseName, i, step)}, pod)
+		if err != nil {
+			if k8s_utils.IsKubernetesResourceNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		pods[i] = pod
+	}
+	return pods, nil
+}
+
+func latestStepPod(pods []*corev1.Pod) int {
+	latest := -1
+	for i, pod := range pods {
+		if pod != nil {
+			latest = i
+		}
+	}
+	return latest
+}
+
+// stepPodsView merges the step pods into the single pod ComputeStatus and followingOrder expect:
+// the container statuses of all of them, the order of the latest and a phase for the whole chain.
+func (pm *PodManager) stepPodsView(pods []*corev1.Pod) *corev1.Pod {
+	latest := latestStepPod(pods)
+	if latest < 0 {
+		if pm.nextOrder(1, nil) > len(pods) {
+			// every step is skipped, there is nothing to run
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{annotationsOrderField: strconv.Itoa(len(pods) + 1)},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+			}
+		}
+		return nil
+	}
+
+	view := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{annotationsOrderField: strconv.Itoa(latest + 1)},
+		},
+	}
+	if isTaskCancelled(pods[latest]) {
+		view.Annotations[annotationsOrderField] = annotationTaskCancelledValue
+	}
+	failed, created := false, 0
+	for _, pod := range pods {
+		if pod == nil {
+			continue
+		}
+		created++
+		if view.Status.StartTime == nil {
+			view.Status.StartTime = pod.Status.StartTime
+		}
+		view.Status.ContainerStatuses = append(view.Status.ContainerStatuses, pod.Status.ContainerStatuses...)
+		failed = failed || pod.Status.Phase == corev1.PodFailed
+	}
+
+	last := pods[latest]
+	// between two step pods the chain is still running
+	view.Status.Phase = corev1.PodRunning
+	next, ok := last.Annotations[annotationsNextOrderField]
+	order, _ := strconv.Atoi(next)
+	switch {
+	case isPodDeadlineExceeded(last):
+		view.Status.Phase = corev1.PodFailed
+		view.Status.Reason = last.Status.Reason
+	case !ok:
+		if last.Status.Phase == corev1.PodPending && created == 1 {
+			view.Status.Phase = corev1.PodPending
+		}
+	case next == annotationTaskExistValue:
+		view.Annotations[annotationsOrderField] = annotationTaskExistValue
+		view.Status.Phase = corev1.PodFailed
+	case order > len(pods):
+		view.Annotations[annotationsOrderField] = next
+		view.Status.Phase = corev1.PodSucceeded
+		if failed {
+			view.Status.Phase = corev1.PodFailed
+		}
+	}
+	return view
+}
@@ -0,0 +1,107 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"strconv"
+	"testing"
+)
+
+// stepPod returns the pod of a step in the given phase, next is its nextOrderField annotation once set.
+func stepPod(phase corev1.PodPhase, next string) *corev1.Pod {
+	pod := &corev1.Pod{Status: corev1.PodStatus{Phase: phase}}
+	if len(next) != 0 {
+		pod.Annotations = map[string]string{annotationsNextOrderField: next}
+	}
+	return pod
+}
+
+func TestStepPodsView(t *testing.T) {
+	tests := []struct {
+		name      string
+		pods      []*corev1.Pod
+		wantNil   bool
+		wantOrder string
+		wantPhase corev1.PodPhase
+	}{
+		{
+			name:    "no pod yet",
+			pods:    []*corev1.Pod{nil, nil},
+			wantNil: true,
+		},
+		{
+			name:      "first step pending",
+			pods:      []*corev1.Pod{stepPod(corev1.PodPending, ""), nil},
+			wantOrder: "1",
+			wantPhase: corev1.PodPending,
+		},
+		{
+			name:      "between two step pods",
+			pods:      []*corev1.Pod{stepPod(corev1.PodSucceeded, "2"), nil},
+			wantOrder: "1",
+			wantPhase: corev1.PodRunning,
+		},
+		{
+			name:      "second step running",
+			pods:      []*corev1.Pod{stepPod(corev1.PodSucceeded, "2"), stepPod(corev1.PodRunning, "")},
+			wantOrder: "2",
+			wantPhase: corev1.PodRunning,
+		},
+		{
+			name:      "done",
+			pods:      []*corev1.Pod{stepPod(corev1.PodSucceeded, "2"), stepPod(corev1.PodSucceeded, "3")},
+			wantOrder: "3",
+			wantPhase: corev1.PodSucceeded,
+		},
+		{
+			// a step allowed to fail leaves its pod failed
+			name:      "done with a failed step",
+			pods:      []*corev1.Pod{stepPod(corev1.PodFailed, "2"), stepPod(corev1.PodSucceeded, "3")},
+			wantOrder: "3",
+			wantPhase: corev1.PodFailed,
+		},
+		{
+			name:      "aborted",
+			pods:      []*corev1.Pod{stepPod(corev1.PodFailed, annotationTaskExistValue), nil},
+			wantOrder: annotationTaskExistValue,
+			wantPhase: corev1.PodFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := newTestPodManager(namedStep("compile"), namedStep("test"))
+			pm.task.Spec.ExecutionMode = v1alpha1.ExecutionModePodPerStep
+			view := pm.stepPodsView(tt.pods)
+			if view == nil {
+				if !tt.wantNil {
+					t.Fatal("expected a view")
+				}
+				return
+			}
+			if tt.wantNil {
+				t.Fatalf("expected no view, got %+v", view)
+			}
+			if order := view.Annotations[annotationsOrderField]; order != tt.wantOrder {
+				t.Errorf("expected order %s, got %s", tt.wantOrder, order)
+			}
+			if view.Status.Phase != tt.wantPhase {
+				t.Errorf("expected phase %s, got %s", tt.wantPhase, view.Status.Phase)
+			}
+		})
+	}
+}
+
+func TestStepPodsViewSkipped(t *testing.T) {
+	staging := whenStep("deploy-staging", v1alpha1.WhenExpression{
+		Input: "$(params.env)", Operator: v1alpha1.WhenOperatorIn, Values: []string{"staging"},
+	})
+	pm := newTestPodManager(staging)
+	pm.task.Spec.ExecutionMode = v1alpha1.ExecutionModePodPerStep
+	pm.task.Spec.Params = []v1alpha1.ParamSpec{{Name: "env", Default: v1alpha1.NewStringParamValue("prod")}}
+
+	// every step is skipped, no pod is ever created
+	view := pm.stepPodsView([]*corev1.Pod{nil})
+	if view == nil || view.Status.Phase != corev1.PodSucceeded || view.Annotations[annotationsOrderField] != strconv.Itoa(2) {
+		t.Errorf("expected a succeeded view past the last step, got %+v", view)
+	}
+}
@@ -0,0 +1,106 @@
+package pod_manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResolveSpec returns the spec the OrderStep runs. An OrderStep referencing a template takes its steps
+// from the snapshot in its status once resolved, only spec.status and spec.approvals are read from
+// its own spec then as they drive a running OrderStep.
+func ResolveSpec(ctx context.Context, reader client.Reader, ot *v1alpha1.OrderStep) (*v1alpha1.OrderStepSpec, error) {
+	if ot.Spec.TemplateRef == nil {
+		return ot.Spec.DeepCopy(), nil
+	}
+	if snapshot := ot.Status.ResolvedSpec; snapshot != nil {
+		spec := snapshot.DeepCopy()
+		spec.Status = ot.Spec.Status
+		spec.Approvals = ot.Spec.Approvals
+		return spec, nil
+	}
+
+	template, err := getTemplate(ctx, reader, ot)
+	if err != nil {
+		return nil, err
+	}
+	// the params a definition leaves unbound are bound by its runs
+	return applyTemplate(&ot.Spec, template, ot.Spec.RunPolicy != v1alpha1.RunPolicyManual)
+}
+
+func getTemplate(ctx context.Context, reader client.Reader, ot *v1alpha1.OrderStep) (*v1alpha1.StepTemplateSpec, error) {
+	ref := ot.Spec.TemplateRef
+	switch ref.Kind {
+	case "", v1alpha1.TemplateKindNamespaced:
+		template := &v1alpha1.OrderStepTemplate{}
+		if err := reader.Get(ctx, types.NamespacedName{Namespace: ot.Namespace, Name: ref.Name}, template); err != nil {
+			return nil, err
+		}
+		return &template.Spec, nil
+	case v1alpha1.TemplateKindCluster:
+		template := &v1alpha1.ClusterOrderStepTemplate{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: ref.Name}, template); err != nil {
+			return nil, err
+		}
+		return &template.Spec, nil
+	default:
+		return nil, fmt.Errorf("unsupported template kind %s", ref.Kind)
+	}
+}
+
+// applyTemplate fills in the spec with the template, a param bound by the templateRef gets
+// the bound value as its default.
+func applyTemplate(spec *v1alpha1.OrderStepSpec, template *v1alpha1.StepTemplateSpec, requireValues bool) (*v1alpha1.OrderStepSpec, error) {
+	resolved := spec.DeepCopy()
+	template = template.DeepCopy()
+	resolved.Steps = template.Steps
+	resolved.Finally = template.Finally
+	resolved.ParallelGroups = template.ParallelGroups
+	resolved.Workspaces = template.Workspaces
+	if resolved.PodTemplate == nil {
+		resolved.PodTemplate = template.PodTemplate
+	}
+	if resolved.ActiveDeadline == nil {
+		resolved.ActiveDeadline = template.ActiveDeadline
+	}
+
+	resolved.Params = template.Params
+	err := bindParams(resolved.Params, spec.TemplateRef.Params, "template "+spec.TemplateRef.Name, requireValues)
+	if err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// bindParams sets the bound values as the defaults of the params, with requireValues every param needs a value then.
+func bindParams(params []v1alpha1.ParamSpec, paramBindings []v1alpha1.ParamBinding, declaredBy string, requireValues bool) error {
+	bindings := make(map[string]v1alpha1.ParamValue, len(paramBindings))
+	for _, binding := range paramBindings {
+		bindings[binding.Name] = binding.Value
+	}
+	for i := range params {
+		param := &params[i]
+		paramType := param.Type
+		if len(paramType) == 0 {
+			paramType = v1alpha1.ParamTypeString
+		}
+		if value, ok := bindings[param.Name]; ok {
+			if value.Type != paramType {
+				return fmt.Errorf("param %s is bound to a %s but is of type %s", param.Name, value.Type, paramType)
+			}
+			param.Default = value.DeepCopy()
+			delete(bindings, param.Name)
+		}
+		if param.Default == nil && requireValues {
+			return fmt.Errorf("param %s has no default and is not bound", param.Name)
+		}
+	}
+	for _, binding := range paramBindings {
+		if _, ok := bindings[binding.Name]; ok {
+			return fmt.Errorf("param %s is not declared by %s", binding.Name, declaredBy)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+// objectReader serves the OrderSteps and OrderStepTemplates of the default namespace.
+type objectReader struct {
+	client.Reader
+	orderSteps map[string]*v1alpha1.OrderStep
+	templates  map[string]*v1alpha1.OrderStepTemplate
+}
+
+func (r *objectReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	if key.Namespace == "default" {
+		switch obj := obj.(type) {
+		case *v1alpha1.OrderStep:
+			if ot, ok := r.orderSteps[key.Name]; ok {
+				ot.DeepCopyInto(obj)
+				return nil
+			}
+		case *v1alpha1.OrderStepTemplate:
+			if template, ok := r.templates[key.Name]; ok {
+				template.DeepCopyInto(obj)
+				return nil
+			}
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+func TestBindParams(t *testing.T) {
+	declared := []v1alpha1.ParamSpec{
+		{Name: "env", Default: v1alpha1.NewStringParamValue("staging")},
+		{Name: "flags", Type: v1alpha1.ParamTypeArray},
+	}
+	flags := v1alpha1.ParamBinding{Name: "flags", Value: *v1alpha1.NewArrayParamValue("-v")}
+	tests := []struct {
+		name          string
+		bindings      []v1alpha1.ParamBinding
+		requireValues bool
+		want          []*v1alpha1.ParamValue
+		wantErr       bool
+	}{
+		{
+			name:          "defaults",
+			bindings:      []v1alpha1.ParamBinding{flags},
+			requireValues: true,
+			want:          []*v1alpha1.ParamValue{v1alpha1.NewStringParamValue("staging"), v1alpha1.NewArrayParamValue("-v")},
+		},
+		{
+			name:          "bound",
+			bindings:      []v1alpha1.ParamBinding{{Name: "env", Value: *v1alpha1.NewStringParamValue("prod")}, flags},
+			requireValues: true,
+			want:          []*v1alpha1.ParamValue{v1alpha1.NewStringParamValue("prod"), v1alpha1.NewArrayParamValue("-v")},
+		},
+		{
+			// the runs bind it
+			name: "left unbound",
+			want: []*v1alpha1.ParamValue{v1alpha1.NewStringParamValue("staging"), nil},
+		},
+		{name: "unbound", requireValues: true, wantErr: true},
+		{
+			name:     "bound to another type",
+			bindings: []v1alpha1.ParamBinding{{Name: "flags", Value: *v1alpha1.NewStringParamValue("-v")}},
+			wantErr:  true,
+		},
+		{
+			name:     "not declared",
+			bindings: []v1alpha1.ParamBinding{{Name: "region", Value: *v1alpha1.NewStringParamValue("eu")}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := make([]v1alpha1.ParamSpec, len(declared))
+			for i := range declared {
+				declared[i].DeepCopyInto(&params[i])
+			}
+			err := bindParams(params, tt.bindings, "template build", tt.requireValues)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected an error: %t, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, want := range tt.want {
+				if !reflect.DeepEqual(params[i].Default, want) {
+					t.Errorf("expected param %s to default to %+v, got %+v", params[i].Name, want, params[i].Default)
+				}
+			}
+			if declared[1].Default != nil {
+				t.Error("expected the declared params to be left alone")
+			}
+		})
+	}
+}
+
+func TestResolveSpec(t *testing.T) {
+	template := &v1alpha1.OrderStepTemplate{Spec: v1alpha1.StepTemplateSpec{
+		Params:         []v1alpha1.ParamSpec{{Name: "env"}},
+		Steps:          []v1alpha1.Step{namedStep("build")},
+		ActiveDeadline: &metav1.Duration{Duration: time.Minute},
+	}}
+	reader := &objectReader{templates: map[string]*v1alpha1.OrderStepTemplate{"build": template}}
+	ref := &v1alpha1.TemplateRef{Name: "build", Params: []v1alpha1.ParamBinding{{Name: "env", Value: *v1alpha1.NewStringParamValue("prod")}}}
+
+	ot := newTestPodManager().task
+	ot.Spec.TemplateRef = ref
+	ot.Spec.ActiveDeadline = &metav1.Duration{Duration: 30 * time.Second}
+	spec, err := ResolveSpec(context.Background(), reader, ot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Steps) != 1 || spec.Steps[0].Name != "build" {
+		t.Errorf("expected the steps of the template, got %+v", spec.Steps)
+	}
+	if spec.ActiveDeadline.Duration != 30*time.Second {
+		t.Errorf("expected the activeDeadline of the OrderStep, got %s", spec.ActiveDeadline.Duration)
+	}
+	if !reflect.DeepEqual(spec.Params[0].Default, v1alpha1.NewStringParamValue("prod")) {
+		t.Errorf("expected the bound param, got %+v", spec.Params[0])
+	}
+	if template.Spec.Params[0].Default != nil {
+		t.Error("expected the template to be left alone")
+	}
+
+	// a resolved OrderStep no longer follows its template
+	ot.Status.ResolvedSpec = spec
+	ot.Spec.Status = v1alpha1.OrderStepSpecStatusPaused
+	template.Spec.Steps = nil
+	spec, err = ResolveSpec(context.Background(), reader, ot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Steps) != 1 || spec.Status != v1alpha1.OrderStepSpecStatusPaused {
+		t.Errorf("expected the snapshot with the status of the OrderStep, got %+v", spec)
+	}
+
+	// the manual runs bind what the definition leaves unbound
+	ot.Status.ResolvedSpec = nil
+	ot.Spec.TemplateRef = &v1alpha1.TemplateRef{Name: "build"}
+	template.Spec.Steps = []v1alpha1.Step{namedStep("build")}
+	if _, err = ResolveSpec(context.Background(), reader, ot); err == nil {
+		t.Error("expected an unbound param to be rejected")
+	}
+	ot.Spec.RunPolicy = v1alpha1.RunPolicyManual
+	if _, err = ResolveSpec(context.Background(), reader, ot); err != nil {
+		t.Errorf("expected an unbound param to be left to the runs, got %v", err)
+	}
+
+	ot.Spec.TemplateRef = &v1alpha1.TemplateRef{Name: "deploy"}
+	if _, err = ResolveSpec(context.Background(), reader, ot); !apierrors.IsNotFound(err) {
+		t.Errorf("expected a missing template to be not found, got %v", err)
+	}
+}
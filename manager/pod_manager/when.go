@@ -0,0 +1,86 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	corev1 "k8s.io/api/core/v1"
+	"strconv"
+)
+
+// variables returns the values the when expressions of a step may refer to through $(...),
+// the string params and what is known of the steps that already ran.
+func (pm *PodManager) variables(pod *corev1.Pod) map[string]string {
+	status := pm.ComputeStatus(pod)
+	return pm.statusVariables(append(status.Steps, status.Finally...))
+}
+
+// statusVariables returns the string params and what the given step statuses tell of the finished steps,
+// a result the step did not write is empty like the entrypoint resolves it.
+func (pm *PodManager) statusVariables(statuses []v1alpha1.StepStatus) map[string]string {
+	vars, _ := pm.paramVariables()
+	steps := pm.allSteps()
+	for i, s := range statuses {
+		if s.FinishedAt == nil {
+			continue
+		}
+		vars["steps."+s.Name+".reason"] = s.Reason
+		if s.ExitCode != nil {
+			vars["steps."+s.Name+".exitCode"] = strconv.Itoa(int(*s.ExitCode))
+		}
+		if i < len(steps) {
+			for _, result := range steps[i].Results {
+				vars["steps."+s.Name+".results."+result.Name] = ""
+			}
+		}
+		for _, result := range s.Results {
+			vars["steps."+s.Name+".results."+result.Name] = result.Value
+		}
+	}
+	return vars
+}
+
+// whenHolds reports whether every when expression of the step holds.
+func whenHolds(step v1alpha1.Step, vars map[string]string) bool {
+	for _, expr := range step.When {
+		input := substitution_util.Replace(expr.Input, vars)
+		in := false
+		for _, v := range expr.Values {
+			if substitution_util.Replace(v, vars) == input {
+				in = true
+				break
+			}
+		}
+		if in != (expr.Operator == v1alpha1.WhenOperatorIn) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextOrder returns the order of the first step from the given one whose when expressions
+// hold, the entrypoints of the steps passed over exit as skipped. It is one past the last
+// container when every remaining step is skipped.
+func (pm *PodManager) nextOrder(from int, pod *corev1.Pod) int {
+	return pm.nextOrderWith(from, func() map[string]string {
+		return pm.variables(pod)
+	})
+}
+
+// nextOrderWith is nextOrder taking the variables from the given function, it is only called
+// once a when expression has to be evaluated.
+func (pm *PodManager) nextOrderWith(from int, variables func() map[string]string) int {
+	steps := pm.allSteps()
+	var vars map[string]string
+	for order := from; order <= len(steps); order++ {
+		if len(steps[order-1].When) == 0 {
+			return order
+		}
+		if vars == nil {
+			vars = variables()
+		}
+		if whenHolds(steps[order-1], vars) {
+			return order
+		}
+	}
+	return len(steps) + 1
+}
@@ -0,0 +1,142 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+)
+
+func whenStep(name string, when ...v1alpha1.WhenExpression) v1alpha1.Step {
+	step := namedStep(name)
+	step.When = when
+	return step
+}
+
+func TestWhenHolds(t *testing.T) {
+	vars := map[string]string{"params.env": "prod", "steps.test.reason": v1alpha1.StepReasonFailed}
+	tests := []struct {
+		name string
+		when []v1alpha1.WhenExpression
+		want bool
+	}{
+		{name: "no expression", want: true},
+		{
+			name: "in",
+			when: []v1alpha1.WhenExpression{{Input: "$(params.env)", Operator: v1alpha1.WhenOperatorIn, Values: []string{"staging", "prod"}}},
+			want: true,
+		},
+		{
+			name: "not in",
+			when: []v1alpha1.WhenExpression{{Input: "$(params.env)", Operator: v1alpha1.WhenOperatorNotIn, Values: []string{"prod"}}},
+		},
+		{
+			name: "values are substituted",
+			when: []v1alpha1.WhenExpression{{Input: "prod", Operator: v1alpha1.WhenOperatorIn, Values: []string{"$(params.env)"}}},
+			want: true,
+		},
+		{
+			name: "every expression must hold",
+			when: []v1alpha1.WhenExpression{
+				{Input: "$(params.env)", Operator: v1alpha1.WhenOperatorIn, Values: []string{"prod"}},
+				{Input: "$(steps.test.reason)", Operator: v1alpha1.WhenOperatorNotIn, Values: []string{v1alpha1.StepReasonFailed}},
+			},
+		},
+		{
+			name: "unknown variable",
+			when: []v1alpha1.WhenExpression{{Input: "$(params.region)", Operator: v1alpha1.WhenOperatorIn, Values: []string{""}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := whenHolds(whenStep("deploy", tt.when...), vars); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextOrderWith(t *testing.T) {
+	prod := v1alpha1.WhenExpression{Input: "$(params.env)", Operator: v1alpha1.WhenOperatorIn, Values: []string{"prod"}}
+	staging := v1alpha1.WhenExpression{Input: "$(params.env)", Operator: v1alpha1.WhenOperatorIn, Values: []string{"staging"}}
+	tests := []struct {
+		name          string
+		steps         []v1alpha1.Step
+		from          int
+		want          int
+		wantVariables bool
+	}{
+		{
+			name:  "no expression",
+			steps: []v1alpha1.Step{namedStep("compile"), namedStep("test")},
+			from:  2,
+			want:  2,
+		},
+		{
+			name:          "holds",
+			steps:         []v1alpha1.Step{namedStep("compile"), whenStep("deploy", prod)},
+			from:          2,
+			want:          2,
+			wantVariables: true,
+		},
+		{
+			name:          "passed over",
+			steps:         []v1alpha1.Step{whenStep("deploy-staging", staging), namedStep("notify")},
+			from:          1,
+			want:          2,
+			wantVariables: true,
+		},
+		{
+			name:          "every remaining step passed over",
+			steps:         []v1alpha1.Step{namedStep("compile"), whenStep("deploy-staging", staging)},
+			from:          2,
+			want:          3,
+			wantVariables: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			got := newTestPodManager(tt.steps...).nextOrderWith(tt.from, func() map[string]string {
+				called = true
+				return map[string]string{"params.env": "prod"}
+			})
+			if got != tt.want {
+				t.Errorf("expected order %d, got %d", tt.want, got)
+			}
+			if called != tt.wantVariables {
+				t.Errorf("expected the variables to be computed: %t, got %t", tt.wantVariables, called)
+			}
+		})
+	}
+}
+
+func TestStatusVariables(t *testing.T) {
+	build := namedStep("build")
+	build.Results = []v1alpha1.StepResult{{Name: "digest"}, {Name: "report"}}
+	deploy := namedStep("deploy")
+	deploy.Results = []v1alpha1.StepResult{{Name: "url"}}
+	pm := newTestPodManager(build, deploy)
+	pm.task.Spec.Params = []v1alpha1.ParamSpec{{Name: "env", Default: v1alpha1.NewStringParamValue("prod")}}
+
+	exitCode := int32(0)
+	now := metav1.Now()
+	got := pm.statusVariables([]v1alpha1.StepStatus{
+		{
+			Name: "build", FinishedAt: &now, ExitCode: &exitCode, Reason: v1alpha1.StepReasonCompleted,
+			Results: []v1alpha1.StepResultValue{{Name: "digest", Value: "sha256:1"}},
+		},
+		{Name: "deploy"},
+	})
+	// a declared result the finished step did not write is empty, the running step is not known yet
+	want := map[string]string{
+		"params.env":                 "prod",
+		"steps.build.reason":         v1alpha1.StepReasonCompleted,
+		"steps.build.exitCode":       "0",
+		"steps.build.results.digest": "sha256:1",
+		"steps.build.results.report": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,124 @@
+package pod_manager
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+)
+
+const (
+	workspaceVolumePrefix = "workspace-"
+	workspaceRootPath     = "/workspace"
+)
+
+// WorkspaceVolumeName returns the name of the pod volume backing the workspace.
+func WorkspaceVolumeName(name string) string {
+	return workspaceVolumePrefix + name
+}
+
+// WorkspaceClaimName returns the name of the PVC created from the volumeClaimTemplate of the workspace.
+func WorkspaceClaimName(baseName, workspaceName string) string {
+	return baseName + "-" + workspaceName
+}
+
+// WorkspaceMountPath returns where the step mounts the workspace.
+func WorkspaceMountPath(ws v1alpha1.WorkspaceDeclaration, usage v1alpha1.WorkspaceUsage) string {
+	if len(usage.MountPath) != 0 {
+		return usage.MountPath
+	}
+	if len(ws.MountPath) != 0 {
+		return ws.MountPath
+	}
+	return path.Join(workspaceRootPath, ws.Name)
+}
+
+func (pm *PodManager) getWorkspace(name string) (v1alpha1.WorkspaceDeclaration, bool) {
+	for _, ws := range pm.task.Spec.Workspaces {
+		if ws.Name == name {
+			return ws, true
+		}
+	}
+	return v1alpha1.WorkspaceDeclaration{}, false
+}
+
+// workspaceVolumes returns a pod volume for every declared workspace.
+func (pm *PodManager) workspaceVolumes() []corev1.Volume {
+	volumes := make([]corev1.Volume, 0, len(pm.task.Spec.Workspaces))
+	for _, ws := range pm.task.Spec.Workspaces {
+		volume := corev1.Volume{Name: WorkspaceVolumeName(ws.Name)}
+		switch {
+		case ws.PersistentVolumeClaim != nil:
+			volume.PersistentVolumeClaim = ws.PersistentVolumeClaim.DeepCopy()
+		case ws.VolumeClaimTemplate != nil:
+			volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: WorkspaceClaimName(pm.baseName, ws.Name),
+			}
+		case ws.ConfigMap != nil:
+			volume.ConfigMap = ws.ConfigMap.DeepCopy()
+		case ws.Secret != nil:
+			volume.Secret = ws.Secret.DeepCopy()
+		default:
+			volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+			if ws.EmptyDir != nil {
+				volume.EmptyDir = ws.EmptyDir.DeepCopy()
+			}
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// workspaceMounts returns the mounts of the workspaces the step requested.
+func (pm *PodManager) workspaceMounts(step v1alpha1.Step) []corev1.VolumeMount {
+	mounts := make([]corev1.VolumeMount, 0, len(step.Workspaces))
+	for _, usage := range step.Workspaces {
+		ws, ok := pm.getWorkspace(usage.Name)
+		if !ok {
+			continue
+		}
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      WorkspaceVolumeName(ws.Name),
+			MountPath: WorkspaceMountPath(ws, usage),
+			SubPath:   usage.SubPath,
+			ReadOnly:  ws.ReadOnly || usage.ReadOnly,
+		})
+	}
+	return mounts
+}
+
+// workspaceVariables returns $(workspaces.<name>.path) for the workspaces mounted by the step.
+func (pm *PodManager) workspaceVariables(step v1alpha1.Step) map[string]string {
+	vars := make(map[string]string, len(step.Workspaces))
+	for _, usage := range step.Workspaces {
+		if ws, ok := pm.getWorkspace(usage.Name); ok {
+			vars["workspaces."+ws.Name+".path"] = WorkspaceMountPath(ws, usage)
+		}
+	}
+	return vars
+}
+
+// createWorkspaceClaims creates the PVCs of the volumeClaimTemplate workspaces, they are owned
+// by the OrderStep so they outlive the pod and go away with the OrderStep.
+func (pm *PodManager) createWorkspaceClaims(ctx context.Context) error {
+	for _, ws := range pm.task.Spec.Workspaces {
+		if ws.VolumeClaimTemplate == nil {
+			continue
+		}
+		pvc := ws.VolumeClaimTemplate.DeepCopy()
+		pvc.ObjectMeta = metav1.ObjectMeta{
+			Name:            WorkspaceClaimName(pm.baseName, ws.Name),
+			Namespace:       pm.task.GetNamespace(),
+			Labels:          ws.VolumeClaimTemplate.Labels,
+			Annotations:     ws.VolumeClaimTemplate.Annotations,
+			OwnerReferences: pm.ownerReferences(),
+		}
+		pvc.Status = corev1.PersistentVolumeClaimStatus{}
+		if err := pm.Client.Create(ctx, pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
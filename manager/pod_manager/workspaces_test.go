@@ -0,0 +1,55 @@
+package pod_manager
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"reflect"
+	"testing"
+)
+
+func TestWorkspaceMounts(t *testing.T) {
+	step := namedStep("compile")
+	step.Workspaces = []v1alpha1.WorkspaceUsage{
+		{Name: "src"},
+		{Name: "cache", MountPath: "/root/.cache", SubPath: "go"},
+		{Name: "config", ReadOnly: true},
+		{Name: "undeclared"},
+	}
+	pm := newTestPodManager(step)
+	pm.baseName = GenerateBaseName(pm.task.Name)
+	pm.task.Spec.Workspaces = []v1alpha1.WorkspaceDeclaration{
+		{Name: "src", VolumeClaimTemplate: &corev1.PersistentVolumeClaim{}},
+		{Name: "cache", MountPath: "/cache"},
+		{Name: "config", MountPath: "/etc/app", ConfigMap: &corev1.ConfigMapVolumeSource{}},
+	}
+
+	wantMounts := []corev1.VolumeMount{
+		{Name: "workspace-src", MountPath: "/workspace/src"},
+		{Name: "workspace-cache", MountPath: "/root/.cache", SubPath: "go"},
+		{Name: "workspace-config", MountPath: "/etc/app", ReadOnly: true},
+	}
+	if got := pm.workspaceMounts(step); !reflect.DeepEqual(got, wantMounts) {
+		t.Errorf("expected mounts %+v, got %+v", wantMounts, got)
+	}
+
+	wantVars := map[string]string{
+		"workspaces.src.path":    "/workspace/src",
+		"workspaces.cache.path":  "/root/.cache",
+		"workspaces.config.path": "/etc/app",
+	}
+	if got := pm.workspaceVariables(step); !reflect.DeepEqual(got, wantVars) {
+		t.Errorf("expected variables %v, got %v", wantVars, got)
+	}
+
+	volumes := pm.workspaceVolumes()
+	if claim := volumes[0].PersistentVolumeClaim; claim == nil || claim.ClaimName != WorkspaceClaimName(pm.baseName, "src") {
+		t.Errorf("expected the volumeClaimTemplate workspace to use its claim, got %+v", volumes[0])
+	}
+	// a workspace without a source is an emptyDir
+	if volumes[1].EmptyDir == nil {
+		t.Errorf("expected an emptyDir, got %+v", volumes[1])
+	}
+	if volumes[2].ConfigMap == nil {
+		t.Errorf("expected a configMap, got %+v", volumes[2])
+	}
+}
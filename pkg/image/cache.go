@@ -0,0 +1,32 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"k8s.io/utils/lru"
+	"runtime"
+)
+
+// OSArch is the platform whose entrypoint is used when a step omits its command.
+var OSArch = fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
+
+// GetImageInfo resolves an image through the cache, the registry is only queried on a miss.
+// Only digests are cached as they always name the same image, a tag may be moved.
+func GetImageInfo(ctx context.Context, cache *lru.Cache, imageName string) (*ImageInfo, error) {
+	ref, err := name.ParseReference(imageName, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := cache.Get(ref); ok {
+		return v.(*ImageInfo), nil
+	}
+	imageInfo, err := ParseImage(ctx, imageName)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ref.(name.Digest); ok {
+		cache.Add(ref, imageInfo)
+	}
+	return imageInfo, nil
+}
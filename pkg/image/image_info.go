@@ -31,6 +31,15 @@ func NewImageInfo(ref registry_name.Reference, name string, digest registry_v1.H
 	}
 }
 
+// Resolve returns what a container of the image runs with args, like the container runtime does:
+// args replace the Cmd, and the Cmd is the command of an image without an Entrypoint.
+func (c *ImageCommand) Resolve(args []string) []string {
+	if len(args) == 0 {
+		args = c.Args
+	}
+	return append(append([]string{}, c.Command...), args...)
+}
+
 func (info *ImageInfo) addImageCommand(os, arch string, cmds []string, args []string) {
 	cmdKey := fmt.Sprintf("%s/%s", strings.ToLower(os), strings.ToLower(arch))
 	info.Command[cmdKey] = &ImageCommand{
@@ -0,0 +1,50 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImageCommandResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		command ImageCommand
+		args    []string
+		want    []string
+	}{
+		{
+			name:    "entrypoint and cmd",
+			command: ImageCommand{Command: []string{"/bin/app"}, Args: []string{"--serve"}},
+			want:    []string{"/bin/app", "--serve"},
+		},
+		{
+			name:    "args replace the cmd",
+			command: ImageCommand{Command: []string{"/bin/app"}, Args: []string{"--serve"}},
+			args:    []string{"--check"},
+			want:    []string{"/bin/app", "--check"},
+		},
+		{
+			name:    "cmd only",
+			command: ImageCommand{Args: []string{"/bin/sh", "-c", "echo"}},
+			want:    []string{"/bin/sh", "-c", "echo"},
+		},
+		{
+			name:    "args run without an entrypoint",
+			command: ImageCommand{Args: []string{"/bin/sh"}},
+			args:    []string{"/bin/echo", "hello"},
+			want:    []string{"/bin/echo", "hello"},
+		},
+		{
+			name:    "neither",
+			command: ImageCommand{},
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.command.Resolve(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,16 +1,18 @@
 package image
 
 import (
+	"context"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
-func ParseImage(img string) (*ImageInfo, error) {
+// ParseImage looks the image up in its registry, the requests are cancelled with ctx.
+func ParseImage(ctx context.Context, img string) (*ImageInfo, error) {
 	ref, err := name.ParseReference(img, name.WeakValidation)
 	if err != nil {
 		return nil, err
 	}
-	des, err := remote.Get(ref)
+	des, err := remote.Get(ref, remote.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -24,6 +26,9 @@ func ParseImage(img string) (*ImageInfo, error) {
 			return nil, err
 		}
 		config, err := image.ConfigFile()
+		if err != nil {
+			return nil, err
+		}
 		imgBuilder.addImageCommand(config.OS, config.Architecture, config.Config.Entrypoint, config.Config.Cmd)
 	}
 	if des.MediaType.IsIndex() {
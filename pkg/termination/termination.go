@@ -0,0 +1,62 @@
+package termination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// MaxMessageSize is how much of the termination log the kubelet keeps, a truncated
+	// message can not be parsed anymore.
+	MaxMessageSize = 4096
+	// MaxAttempts bounds the attempts of a step so that they fit in the termination log.
+	MaxAttempts = 10
+)
+
+var ErrMessageTooLarge = errors.New("termination message too large")
+
+// Attempt is one execution of a step command by the entrypoint.
+type Attempt struct {
+	ExitCode  int32         `json:"exitCode"`
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`
+	TimedOut  bool          `json:"timedOut,omitempty"`
+	Cancelled bool          `json:"cancelled,omitempty"`
+}
+
+// Message is what the entrypoint leaves in the termination log of its container,
+// the kubelet hands it to the controller through ContainerStateTerminated.Message.
+type Message struct {
+	Reason   string    `json:"reason,omitempty"`
+	Attempts []Attempt `json:"attempts,omitempty"`
+	// Results are the declared results the step wrote, keyed by name.
+	Results map[string]string `json:"results,omitempty"`
+}
+
+// Write leaves the message in the termination log, it fails with ErrMessageTooLarge rather
+// than writing a message the kubelet would truncate.
+func Write(path string, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes, at most %d are kept", ErrMessageTooLarge, len(data), MaxMessageSize)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Parse returns nil when the container did not terminate through the entrypoint.
+func Parse(message string) *Message {
+	if len(message) == 0 {
+		return nil
+	}
+	msg := &Message{}
+	if err := json.Unmarshal([]byte(message), msg); err != nil {
+		return nil
+	}
+	return msg
+}
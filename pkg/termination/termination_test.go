@@ -0,0 +1,72 @@
+package termination
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    *Message
+	}{
+		{name: "empty"},
+		{name: "not written by the entrypoint", message: "Error: exit status 1"},
+		{name: "truncated", message: `{"reason":"Failed","attempts":[{"exitCode":1`},
+		{
+			name:    "attempts and results",
+			message: `{"reason":"Failed","attempts":[{"exitCode":1,"startedAt":"2023-01-02T03:04:05Z","duration":1000000000}],"results":{"digest":"sha256:1"}}`,
+			want: &Message{
+				Reason:   "Failed",
+				Attempts: []Attempt{{ExitCode: 1, StartedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), Duration: time.Second}},
+				Results:  map[string]string{"digest": "sha256:1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     *Message
+		wantErr error
+	}{
+		{name: "attempts", msg: &Message{Attempts: make([]Attempt, MaxAttempts)}},
+		{name: "results too large", msg: &Message{Results: map[string]string{"log": strings.Repeat("a", MaxMessageSize)}}, wantErr: ErrMessageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "termination-log")
+			err := Write(path, tt.msg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("expected nothing to be written, got %v", err)
+				}
+				return
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Parse(string(data)); !reflect.DeepEqual(got, tt.msg) {
+				t.Errorf("expected %+v to be read back, got %+v", tt.msg, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package k8s_utils
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sErr "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"time"
+)
+
+// CreateCustomResourceDefinition creates the CRD, or updates the installed one to its spec, and waits
+// till it is established. A CRD created here is deleted again when it does not get established.
+func CreateCustomResourceDefinition(ctx context.Context, apiextCli *apiextensionsclient.Clientset,
+	crd *apiextensionsv1.CustomResourceDefinition, logger logr.Logger) error {
+	crdCli := apiextCli.ApiextensionsV1().CustomResourceDefinitions()
+	name := crd.GetName()
+	created := true
+	_, err := crdCli.Create(ctx, crd, metav1.CreateOptions{})
+	if err != nil {
+		if !IsKubernetesResourceAlreadyExistError(err) {
+			return err
+		}
+		// keep the installed schema in step with the operator version
+		created = false
+		existing, err := crdCli.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		existing.Spec = crd.Spec
+		if _, err = crdCli.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	// wait for the crd resource being created
+	logger.Info("creating crd resource, wating till its established", "crd", name)
+	err = wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, 60*time.Second, false, func(ctx context.Context) (done bool, err error) {
+		crd, err = crdCli.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range crd.Status.Conditions {
+			switch cond.Type {
+			case apiextensionsv1.Established:
+				if cond.Status == apiextensionsv1.ConditionTrue {
+					return true, err
+				}
+			case apiextensionsv1.NamesAccepted:
+				if cond.Status == apiextensionsv1.ConditionFalse {
+					//otc.logger.WithName().
+				}
+			}
+		}
+		return false, err
+	})
+	if err != nil && created {
+		deleteErr := crdCli.Delete(ctx, name, metav1.DeleteOptions{})
+		if deleteErr != nil {
+			return k8sErr.NewAggregate([]error{err, deleteErr})
+		}
+		return err
+	}
+	return nil
+}
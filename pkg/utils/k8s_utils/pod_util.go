@@ -16,7 +16,7 @@ func RetryCreateAndWaitPod(ctx context.Context, cli client.Client, pod *corev1.P
 	if err = cli.Create(ctx, pod); err != nil {
 		return nil, err
 	}
-	var retPod *corev1.Pod
+	retPod := &corev1.Pod{}
 	err = retry_util.Retry(interval, maxRetries, func() (bool, error) {
 		err := cli.Get(ctx, client.ObjectKeyFromObject(pod), retPod)
 		if err != nil {
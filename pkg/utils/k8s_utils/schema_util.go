@@ -0,0 +1,165 @@
+package k8s_utils
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"reflect"
+	"strings"
+)
+
+var (
+	timeType        = reflect.TypeOf(metav1.Time{})
+	microTimeType   = reflect.TypeOf(metav1.MicroTime{})
+	durationType    = reflect.TypeOf(metav1.Duration{})
+	objectMetaType  = reflect.TypeOf(metav1.ObjectMeta{})
+	quantityType    = reflect.TypeOf(resource.Quantity{})
+	intOrStringType = reflect.TypeOf(intstr.IntOrString{})
+	rawExtType      = reflect.TypeOf(runtime.RawExtension{})
+)
+
+// StructuralSchemaOf derives a structural openAPIV3Schema from a go type using its json tags,
+// the same way controller-gen does: fields without omitempty are required.
+func StructuralSchemaOf(t reflect.Type) *apiextensionsv1.JSONSchemaProps {
+	props := schemaOf(t)
+	// the api server validates the metadata of the object itself, its schema may not say more
+	if _, ok := props.Properties["metadata"]; ok {
+		props.Properties["metadata"] = apiextensionsv1.JSONSchemaProps{Type: "object"}
+	}
+	return &props
+}
+
+// embeddedObjectMetaSchema keeps the metadata fields of an embedded object such as a
+// volumeClaimTemplate, like controller-gen does with generateEmbeddedObjectMeta.
+func embeddedObjectMetaSchema() apiextensionsv1.JSONSchemaProps {
+	stringMap := apiextensionsv1.JSONSchemaProps{
+		Type:                 "object",
+		AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}},
+	}
+	return apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"name":        {Type: "string"},
+			"namespace":   {Type: "string"},
+			"labels":      stringMap,
+			"annotations": stringMap,
+			"finalizers": {
+				Type:  "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}},
+			},
+		},
+	}
+}
+
+func schemaOf(t reflect.Type) apiextensionsv1.JSONSchemaProps {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType, microTimeType:
+		return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "date-time"}
+	case durationType:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}
+	case objectMetaType:
+		return embeddedObjectMetaSchema()
+	case quantityType, intOrStringType:
+		return apiextensionsv1.JSONSchemaProps{
+			XIntOrString: true,
+			AnyOf: []apiextensionsv1.JSONSchemaProps{
+				{Type: "integer"},
+				{Type: "string"},
+			},
+		}
+	case rawExtType:
+		return apiextensionsv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: pointerTrue()}
+	}
+
+	// types with their own json representation tell their schema the way kube-openapi expects it
+	if openAPIType, ok := reflect.New(t).Elem().Interface().(interface{ OpenAPISchemaType() []string }); ok {
+		types := openAPIType.OpenAPISchemaType()
+		if len(types) != 1 {
+			return apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: pointerTrue()}
+		}
+		props := apiextensionsv1.JSONSchemaProps{Type: types[0]}
+		if format, ok := openAPIType.(interface{ OpenAPISchemaFormat() string }); ok {
+			props.Format = format.OpenAPISchemaFormat()
+		}
+		return props
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}
+	case reflect.Bool:
+		return apiextensionsv1.JSONSchemaProps{Type: "boolean"}
+	case reflect.Int32, reflect.Uint32, reflect.Int16, reflect.Uint16, reflect.Int8, reflect.Uint8:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return apiextensionsv1.JSONSchemaProps{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "byte"}
+		}
+		items := schemaOf(t.Elem())
+		return apiextensionsv1.JSONSchemaProps{
+			Type:  "array",
+			Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &items},
+		}
+	case reflect.Map:
+		values := schemaOf(t.Elem())
+		return apiextensionsv1.JSONSchemaProps{
+			Type:                 "object",
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &values},
+		}
+	case reflect.Struct:
+		props := apiextensionsv1.JSONSchemaProps{
+			Type:       "object",
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{},
+		}
+		addStructFields(&props, t)
+		return props
+	default:
+		// interface{} and friends, nothing can be said about their shape
+		return apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: pointerTrue()}
+	}
+}
+
+func addStructFields(props *apiextensionsv1.JSONSchemaProps, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) != 0 && !field.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && len(name) == 0 && fieldType.Kind() == reflect.Struct {
+			addStructFields(props, fieldType)
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		props.Properties[name] = schemaOf(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			props.Required = append(props.Required, name)
+		}
+	}
+}
+
+func pointerTrue() *bool {
+	b := true
+	return &b
+}
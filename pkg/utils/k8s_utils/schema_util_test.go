@@ -0,0 +1,128 @@
+package k8s_utils
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"testing"
+)
+
+type schemaTestInline struct {
+	Inlined string `json:"inlined"`
+}
+
+type schemaTestSpec struct {
+	schemaTestInline `json:",inline"`
+
+	Name     string            `json:"name"`
+	Count    *int32            `json:"count,omitempty"`
+	Size     int64             `json:"size,omitempty"`
+	Ratio    float64           `json:"ratio,omitempty"`
+	Enabled  bool              `json:"enabled,omitempty"`
+	Data     []byte            `json:"data,omitempty"`
+	Items    []string          `json:"items,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	At       *metav1.Time      `json:"at,omitempty"`
+	Timeout  metav1.Duration   `json:"timeout,omitempty"`
+	Memory   resource.Quantity `json:"memory,omitempty"`
+	Raw      runtime.RawExtension
+	Any      interface{}    `json:"any,omitempty"`
+	Ignored  string         `json:"-"`
+	Template schemaTestMeta `json:"template,omitempty"`
+	private  string
+}
+
+type schemaTestMeta struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+type schemaTestObject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec schemaTestSpec `json:"spec"`
+}
+
+func TestStructuralSchemaOf(t *testing.T) {
+	schema := StructuralSchemaOf(reflect.TypeOf(schemaTestObject{}))
+	if schema.Type != "object" {
+		t.Fatalf("expected an object, got %q", schema.Type)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"spec"}) {
+		t.Errorf("expected only spec to be required, got %v", schema.Required)
+	}
+	if metadata := schema.Properties["metadata"]; !reflect.DeepEqual(metadata, apiextensionsv1.JSONSchemaProps{Type: "object"}) {
+		t.Errorf("expected the metadata of the object to be a bare object, got %+v", metadata)
+	}
+	for _, name := range []string{"apiVersion", "kind"} {
+		if schema.Properties[name].Type != "string" {
+			t.Errorf("expected %s of the inlined TypeMeta to be a string, got %+v", name, schema.Properties[name])
+		}
+	}
+
+	spec := schema.Properties["spec"]
+	if !reflect.DeepEqual(spec.Required, []string{"inlined", "name", "Raw"}) {
+		t.Errorf("expected the fields without omitempty to be required, got %v", spec.Required)
+	}
+	tests := []struct {
+		field  string
+		want   string
+		format string
+	}{
+		{"inlined", "string", ""},
+		{"name", "string", ""},
+		{"count", "integer", "int32"},
+		{"size", "integer", "int64"},
+		{"ratio", "number", ""},
+		{"enabled", "boolean", ""},
+		{"data", "string", "byte"},
+		{"items", "array", ""},
+		{"labels", "object", ""},
+		{"at", "string", "date-time"},
+		{"timeout", "string", ""},
+		{"Raw", "object", ""},
+		{"template", "object", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			props, ok := spec.Properties[tt.field]
+			if !ok {
+				t.Fatalf("field %s is missing", tt.field)
+			}
+			if props.Type != tt.want || props.Format != tt.format {
+				t.Errorf("expected %s/%s, got %s/%s", tt.want, tt.format, props.Type, props.Format)
+			}
+		})
+	}
+
+	for _, name := range []string{"Ignored", "-", "private"} {
+		if _, ok := spec.Properties[name]; ok {
+			t.Errorf("expected %s to be left out", name)
+		}
+	}
+	if items := spec.Properties["items"].Items; items == nil || items.Schema.Type != "string" {
+		t.Errorf("expected the items to be strings, got %+v", items)
+	}
+	if values := spec.Properties["labels"].AdditionalProperties; values == nil || values.Schema.Type != "string" {
+		t.Errorf("expected the label values to be strings, got %+v", values)
+	}
+	if memory := spec.Properties["memory"]; !memory.XIntOrString || len(memory.AnyOf) != 2 {
+		t.Errorf("expected a quantity to be an int or string, got %+v", memory)
+	}
+	if raw := spec.Properties["Raw"]; raw.XPreserveUnknownFields == nil || !*raw.XPreserveUnknownFields {
+		t.Errorf("expected a RawExtension to preserve unknown fields, got %+v", raw)
+	}
+	if anything := spec.Properties["any"]; anything.XPreserveUnknownFields == nil || !*anything.XPreserveUnknownFields {
+		t.Errorf("expected an interface to preserve unknown fields, got %+v", anything)
+	}
+
+	// the metadata of an embedded object keeps its labels and annotations
+	metadata := spec.Properties["template"].Properties["metadata"]
+	for _, name := range []string{"name", "namespace", "labels", "annotations", "finalizers"} {
+		if _, ok := metadata.Properties[name]; !ok {
+			t.Errorf("expected the embedded metadata to declare %s, got %+v", name, metadata)
+		}
+	}
+}
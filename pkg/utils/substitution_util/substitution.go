@@ -0,0 +1,70 @@
+package substitution_util
+
+import (
+	"regexp"
+)
+
+var (
+	variableRegexp = regexp.MustCompile(`\$\(([a-zA-Z0-9_.\-]+)\)`)
+	arrayRegexp    = regexp.MustCompile(`^\$\(([a-zA-Z0-9_.\-]+)\[\*\]\)$`)
+)
+
+// Replace substitutes every $(name) in s with vars[name], unknown variables are left as they are.
+func Replace(s string, vars map[string]string) string {
+	return variableRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		name := variableRegexp.FindStringSubmatch(match)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return match
+	})
+}
+
+// ReplaceAll applies Replace to every element of ss.
+func ReplaceAll(ss []string, vars map[string]string) []string {
+	if ss == nil {
+		return nil
+	}
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, Replace(s, vars))
+	}
+	return out
+}
+
+// ReplaceWithArrays is ReplaceAll where an element that is exactly $(name[*]) is expanded
+// into the elements of arrays[name].
+func ReplaceWithArrays(ss []string, vars map[string]string, arrays map[string][]string) []string {
+	if ss == nil {
+		return nil
+	}
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if match := arrayRegexp.FindStringSubmatch(s); match != nil {
+			if values, ok := arrays[match[1]]; ok {
+				out = append(out, values...)
+				continue
+			}
+		}
+		out = append(out, Replace(s, vars))
+	}
+	return out
+}
+
+// References returns the names of the variables referenced in s as $(name).
+func References(s string) []string {
+	var names []string
+	for _, match := range variableRegexp.FindAllStringSubmatch(s, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// ArrayReference returns the name of the array when s is exactly $(name[*]).
+func ArrayReference(s string) (string, bool) {
+	match := arrayRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
@@ -0,0 +1,115 @@
+package substitution_util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	vars := map[string]string{"params.env": "prod", "steps.build.results.digest": "sha256:1", "empty": ""}
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "no variable", s: "echo hello", want: "echo hello"},
+		{name: "param", s: "deploy --env $(params.env)", want: "deploy --env prod"},
+		{name: "result", s: "$(steps.build.results.digest)", want: "sha256:1"},
+		{name: "several", s: "$(params.env)-$(params.env)", want: "prod-prod"},
+		{name: "empty value", s: "a$(empty)b", want: "ab"},
+		{name: "unknown left alone", s: "$(params.region)", want: "$(params.region)"},
+		{name: "shell substitution left alone", s: "$(date +%s)", want: "$(date +%s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Replace(tt.s, vars); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplaceAll(t *testing.T) {
+	vars := map[string]string{"params.env": "prod"}
+	tests := []struct {
+		name string
+		ss   []string
+		want []string
+	}{
+		{name: "nil"},
+		{name: "empty", ss: []string{}, want: []string{}},
+		{name: "every element", ss: []string{"--env", "$(params.env)", "$(params.region)"}, want: []string{"--env", "prod", "$(params.region)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReplaceAll(tt.ss, vars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{name: "none", s: "echo $HOME"},
+		{name: "one", s: "$(params.env)", want: []string{"params.env"}},
+		{name: "in order", s: "$(steps.build.results.digest) $(params.env)", want: []string{"steps.build.results.digest", "params.env"}},
+		{name: "not a name", s: "$(date +%s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := References(tt.s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplaceWithArrays(t *testing.T) {
+	vars := map[string]string{"params.env": "prod"}
+	arrays := map[string][]string{"params.flags": {"-v", "-race"}, "params.none": {}}
+	tests := []struct {
+		name string
+		ss   []string
+		want []string
+	}{
+		{name: "nil"},
+		{name: "expanded", ss: []string{"test", "$(params.flags[*])", "./..."}, want: []string{"test", "-v", "-race", "./..."}},
+		{name: "empty array", ss: []string{"test", "$(params.none[*])"}, want: []string{"test"}},
+		{name: "strings replaced", ss: []string{"--env=$(params.env)", "$(params.flags[*])"}, want: []string{"--env=prod", "-v", "-race"}},
+		{name: "only whole elements", ss: []string{"--flags=$(params.flags[*])"}, want: []string{"--flags=$(params.flags[*])"}},
+		{name: "unknown array", ss: []string{"$(params.tags[*])"}, want: []string{"$(params.tags[*])"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReplaceWithArrays(tt.ss, vars, arrays); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestArrayReference(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   string
+		wantOk bool
+	}{
+		{s: "$(params.flags[*])", want: "params.flags", wantOk: true},
+		{s: "$(params.flags)"},
+		{s: "-$(params.flags[*])"},
+		{s: "$(params.flags[0])"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := ArrayReference(tt.s)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("expected %q/%t, got %q/%t", tt.want, tt.wantOk, got, ok)
+			}
+		})
+	}
+}
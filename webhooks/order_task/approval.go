@@ -0,0 +1,95 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// validateApprovalStep checks an approval step sets nothing its entrypoint would have to run.
+func validateApprovalStep(step v1alpha1.Step, finally bool, idxPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if finally {
+		allErrs = append(allErrs, field.Forbidden(idxPath.Child("approval"), "finally steps can not be approval steps"))
+	}
+	forbidden := map[string]bool{
+		"image":      len(step.Image) != 0,
+		"command":    len(step.Command) != 0,
+		"args":       len(step.Args) != 0,
+		"script":     len(step.Script) != 0,
+		"results":    len(step.Results) != 0,
+		"retries":    step.Retries != 0,
+		"timeout":    step.Timeout != nil,
+		"workspaces": len(step.Workspaces) != 0,
+	}
+	for _, name := range []string{"image", "command", "args", "script", "results", "retries", "timeout", "workspaces"} {
+		if forbidden[name] {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child(name), "not allowed on an approval step"))
+		}
+	}
+	return allErrs
+}
+
+// validateApprovals checks every approval signs off an approval step once, the approvals already
+// recorded are left untouched and the new ones are recorded with the user adding them. An approval
+// can only be added once the object exists, nobody could have approved it before.
+func validateApprovals(ctx context.Context, ot, old *v1alpha1.OrderStep) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec", "approvals")
+	if old == nil {
+		if len(ot.Spec.Approvals) != 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "can only be added once created"))
+		}
+		return allErrs
+	}
+
+	gates := make(map[string]bool, len(ot.Spec.Steps))
+	for i, step := range ot.Spec.Steps {
+		if step.Approval != nil {
+			gates[pod_manager.StepName(i, step)] = true
+		}
+	}
+	recorded := make(map[string]v1alpha1.Approval)
+	for _, approval := range old.Spec.Approvals {
+		recorded[approval.Step] = approval
+	}
+	username := ""
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		username = req.UserInfo.Username
+	}
+
+	seen := make(map[string]bool, len(ot.Spec.Approvals))
+	for i, approval := range ot.Spec.Approvals {
+		idxPath := fldPath.Index(i)
+		if !gates[approval.Step] {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("step"), approval.Step, "must be the name of an approval step"))
+		}
+		if seen[approval.Step] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("step"), approval.Step))
+		}
+		seen[approval.Step] = true
+
+		if previous, ok := recorded[approval.Step]; ok {
+			if !equality.Semantic.DeepEqual(previous, approval) {
+				allErrs = append(allErrs, field.Forbidden(idxPath, "a recorded approval can not be changed"))
+			}
+			continue
+		}
+		if len(username) != 0 && approval.ApprovedBy != username {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("approvedBy"), approval.ApprovedBy, "must be the user adding the approval"))
+		}
+		if approval.ApprovedAt == nil {
+			allErrs = append(allErrs, field.Required(idxPath.Child("approvedAt"), "set to when the approval is added"))
+		}
+	}
+	for name := range recorded {
+		if !seen[name] {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "a recorded approval can not be removed"))
+			break
+		}
+	}
+	return allErrs
+}
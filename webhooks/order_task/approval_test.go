@@ -0,0 +1,150 @@
+package order_task
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+	"time"
+)
+
+func approvalStep(name string) v1alpha1.Step {
+	step := v1alpha1.Step{Approval: &v1alpha1.ApprovalGate{Message: "release to production"}}
+	step.Name = name
+	return step
+}
+
+// userContext returns the context of an admission request sent by username, old is the object updated.
+func userContext(t *testing.T, username string, old *v1alpha1.OrderStep) context.Context {
+	t.Helper()
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		UserInfo:  authenticationv1.UserInfo{Username: username},
+	}}
+	if old != nil {
+		raw, err := json.Marshal(old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Operation, req.OldObject = admissionv1.Update, runtime.RawExtension{Raw: raw}
+	}
+	return admission.NewContextWithRequest(context.Background(), req)
+}
+
+func TestValidateApprovalStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(step *v1alpha1.Step)
+		finally bool
+		want    []string
+	}{
+		{name: "gate only", modify: func(step *v1alpha1.Step) {}},
+		{name: "finally step", modify: func(step *v1alpha1.Step) {}, finally: true, want: []string{"spec.steps[0].approval"}},
+		{
+			name: "something to run",
+			modify: func(step *v1alpha1.Step) {
+				step.Image, step.Command = "alpine:3.18", []string{"true"}
+				step.Retries = 1
+			},
+			want: []string{"spec.steps[0].image", "spec.steps[0].command", "spec.steps[0].retries"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := approvalStep("release")
+			tt.modify(&step)
+			expectFields(t, validateApprovalStep(step, tt.finally, field.NewPath("spec", "steps").Index(0)), tt.want...)
+		})
+	}
+}
+
+func TestValidateApprovals(t *testing.T) {
+	approvedAt := metav1.NewTime(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	recorded := v1alpha1.Approval{Step: "release", ApprovedBy: "alice", ApprovedAt: &approvedAt}
+	tests := []struct {
+		name      string
+		recorded  []v1alpha1.Approval
+		approvals []v1alpha1.Approval
+		want      []string
+	}{
+		{name: "none"},
+		{name: "added", approvals: []v1alpha1.Approval{{Step: "release", ApprovedBy: "bob", ApprovedAt: &approvedAt}}},
+		{name: "kept", recorded: []v1alpha1.Approval{recorded}, approvals: []v1alpha1.Approval{recorded}},
+		{
+			name:      "not an approval step",
+			approvals: []v1alpha1.Approval{{Step: "build", ApprovedBy: "bob", ApprovedAt: &approvedAt}},
+			want:      []string{"spec.approvals[0].step"},
+		},
+		{
+			name: "twice",
+			approvals: []v1alpha1.Approval{
+				{Step: "release", ApprovedBy: "bob", ApprovedAt: &approvedAt},
+				{Step: "release", ApprovedBy: "bob", ApprovedAt: &approvedAt},
+			},
+			want: []string{"spec.approvals[1].step"},
+		},
+		{
+			name:      "on behalf of another user",
+			approvals: []v1alpha1.Approval{{Step: "release", ApprovedBy: "alice", ApprovedAt: &approvedAt}},
+			want:      []string{"spec.approvals[0].approvedBy"},
+		},
+		{
+			name:      "without approvedAt",
+			approvals: []v1alpha1.Approval{{Step: "release", ApprovedBy: "bob"}},
+			want:      []string{"spec.approvals[0].approvedAt"},
+		},
+		{
+			name:      "recorded changed",
+			recorded:  []v1alpha1.Approval{recorded},
+			approvals: []v1alpha1.Approval{{Step: "release", ApprovedBy: "bob", ApprovedAt: &approvedAt}},
+			want:      []string{"spec.approvals[0]"},
+		},
+		{
+			name:     "recorded removed",
+			recorded: []v1alpha1.Approval{recorded},
+			want:     []string{"spec.approvals"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := newOrderStep(commandStep("build"), approvalStep("release"))
+			old.Spec.Approvals = tt.recorded
+			ot := old.DeepCopy()
+			ot.Spec.Approvals = tt.approvals
+			expectFields(t, validateApprovals(userContext(t, "bob", old), ot, old), tt.want...)
+		})
+	}
+}
+
+func TestValidateApprovalsOnCreate(t *testing.T) {
+	ot := newOrderStep(commandStep("build"), approvalStep("release"))
+	ot.Spec.Approvals = []v1alpha1.Approval{{Step: "release", ApprovedBy: "bob"}}
+	expectFields(t, validateApprovals(userContext(t, "bob", nil), ot, nil), "spec.approvals")
+}
+
+func TestDefaultApprovals(t *testing.T) {
+	approvedAt := metav1.NewTime(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	recorded := v1alpha1.Approval{Step: "release", ApprovedBy: "alice", ApprovedAt: &approvedAt}
+	old := newOrderStep(commandStep("build"), approvalStep("release"), approvalStep("promote"))
+	old.Spec.Approvals = []v1alpha1.Approval{recorded}
+
+	// the request claims to be from alice, it is recorded as sent by bob
+	approvals := []v1alpha1.Approval{recorded, {Step: "promote", ApprovedBy: "alice", ApprovedAt: &approvedAt}}
+	defaultApprovals(userContext(t, "bob", old), approvals)
+
+	if approvals[0] != recorded {
+		t.Errorf("expected the recorded approval to be left alone, got %+v", approvals[0])
+	}
+	if approvals[1].ApprovedBy != "bob" {
+		t.Errorf("expected the approval to be recorded as added by bob, got %s", approvals[1].ApprovedBy)
+	}
+	if approvals[1].ApprovedAt == nil || approvals[1].ApprovedAt.Equal(&approvedAt) {
+		t.Errorf("expected the approval to be recorded as added now, got %v", approvals[1].ApprovedAt)
+	}
+}
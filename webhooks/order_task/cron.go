@@ -0,0 +1,123 @@
+package order_task
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/controllers/cron_order_step"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"time"
+)
+
+var _ webhook.CustomValidator = &CronValidator{}
+
+// CronValidator rejects CronOrderSteps whose schedule can not be read or whose OrderSteps could not be
+// created, the controller would only find out once they are due.
+type CronValidator struct {
+	*OrderStepValidator
+}
+
+func NewCronValidator(validator *OrderStepValidator) *CronValidator {
+	return &CronValidator{OrderStepValidator: validator}
+}
+
+func (v *CronValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validateCron(ctx, obj, nil)
+}
+
+func (v *CronValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*v1alpha1.CronOrderStep)
+	if !ok {
+		return nil, fmt.Errorf("expected a CronOrderStep but got a %T", oldObj)
+	}
+	return v.validateCron(ctx, newObj, old)
+}
+
+func (v *CronValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *CronValidator) validateCron(ctx context.Context, obj runtime.Object, old *v1alpha1.CronOrderStep) (admission.Warnings, error) {
+	cos, ok := obj.(*v1alpha1.CronOrderStep)
+	if !ok {
+		return nil, fmt.Errorf("expected a CronOrderStep but got a %T", obj)
+	}
+
+	allErrs := validateCronSpec(&cos.Spec)
+	// the OrderSteps are labelled with the name and named after it and the minute they are scheduled at,
+	// the suffix only gets longer in the 22nd century
+	fldPath := field.NewPath("metadata", "name")
+	for _, msg := range validation.IsValidLabelValue(cos.GetName()) {
+		allErrs = append(allErrs, field.Invalid(fldPath, cos.GetName(), "label value "+msg))
+	}
+	name := cron_order_step.OrderStepName(cos, time.Now())
+	allErrs = append(allErrs, validateName(pod_manager.GenerateBaseName(name), name, fldPath)...)
+
+	// the OrderSteps are validated on create like any other, the template is checked up front
+	if old == nil || !equality.Semantic.DeepEqual(cos.Spec.Template, old.Spec.Template) {
+		template := cos.Spec.Template.DeepCopy()
+		ot := &v1alpha1.OrderStep{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   cos.GetNamespace(),
+				Labels:      template.Labels,
+				Annotations: template.Annotations,
+			},
+			Spec: template.Spec,
+		}
+		for _, err := range v.validateOrderStep(ctx, ot, nil) {
+			err.Field = "spec.template." + err.Field
+			allErrs = append(allErrs, err)
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil, nil
+	}
+	return nil, apierrors.NewInvalid(
+		schema.GroupKind{Group: v1alpha1.OrderTaskGroup, Kind: v1alpha1.CronOrderStepResourceKind},
+		cos.GetName(), allErrs)
+}
+
+// validateCronSpec checks the schedule, the time zone it is read in and the policies.
+func validateCronSpec(spec *v1alpha1.CronOrderStepSpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec")
+	if len(spec.Schedule) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("schedule"), ""))
+	} else if schedule, err := cron.ParseStandard(spec.Schedule); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("schedule"), spec.Schedule, err.Error()))
+	} else if schedule.Next(time.Now()).IsZero() {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("schedule"), spec.Schedule, "never fires"))
+	}
+	if tz := spec.TimeZone; tz != nil && len(*tz) != 0 {
+		if _, err := time.LoadLocation(*tz); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("timeZone"), *tz, err.Error()))
+		}
+	}
+	switch spec.ConcurrencyPolicy {
+	case "", v1alpha1.ConcurrencyPolicyAllow, v1alpha1.ConcurrencyPolicyForbid, v1alpha1.ConcurrencyPolicyReplace:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("concurrencyPolicy"), spec.ConcurrencyPolicy,
+			[]string{string(v1alpha1.ConcurrencyPolicyAllow), string(v1alpha1.ConcurrencyPolicyForbid), string(v1alpha1.ConcurrencyPolicyReplace)}))
+	}
+	if deadline := spec.StartingDeadlineSeconds; deadline != nil && *deadline < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("startingDeadlineSeconds"), *deadline, "must be greater than or equal to 0"))
+	}
+	if limit := spec.SuccessfulHistoryLimit; limit != nil && *limit < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("successfulHistoryLimit"), *limit, "must be greater than or equal to 0"))
+	}
+	if limit := spec.FailedHistoryLimit; limit != nil && *limit < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("failedHistoryLimit"), *limit, "must be greater than or equal to 0"))
+	}
+	return allErrs
+}
@@ -0,0 +1,104 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"strings"
+	"testing"
+)
+
+func TestValidateCronSpec(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(spec *v1alpha1.CronOrderStepSpec)
+		want   []string
+	}{
+		{name: "valid", modify: func(spec *v1alpha1.CronOrderStepSpec) {}},
+		{
+			name: "every option",
+			modify: func(spec *v1alpha1.CronOrderStepSpec) {
+				spec.TimeZone = pointer.String("Europe/Paris")
+				spec.ConcurrencyPolicy = v1alpha1.ConcurrencyPolicyForbid
+				spec.StartingDeadlineSeconds = pointer.Int64(60)
+				spec.SuccessfulHistoryLimit, spec.FailedHistoryLimit = pointer.Int32(0), pointer.Int32(0)
+			},
+		},
+		{name: "no schedule", modify: func(spec *v1alpha1.CronOrderStepSpec) { spec.Schedule = "" }, want: []string{"spec.schedule"}},
+		{name: "invalid schedule", modify: func(spec *v1alpha1.CronOrderStepSpec) { spec.Schedule = "0 2 * *" }, want: []string{"spec.schedule"}},
+		{name: "schedule never firing", modify: func(spec *v1alpha1.CronOrderStepSpec) { spec.Schedule = "0 0 30 2 *" }, want: []string{"spec.schedule"}},
+		{
+			name:   "unknown timeZone",
+			modify: func(spec *v1alpha1.CronOrderStepSpec) { spec.TimeZone = pointer.String("Mars/Olympus") },
+			want:   []string{"spec.timeZone"},
+		},
+		{
+			name:   "unknown concurrencyPolicy",
+			modify: func(spec *v1alpha1.CronOrderStepSpec) { spec.ConcurrencyPolicy = "Queue" },
+			want:   []string{"spec.concurrencyPolicy"},
+		},
+		{
+			name: "negative values",
+			modify: func(spec *v1alpha1.CronOrderStepSpec) {
+				spec.StartingDeadlineSeconds = pointer.Int64(-1)
+				spec.SuccessfulHistoryLimit, spec.FailedHistoryLimit = pointer.Int32(-1), pointer.Int32(-1)
+			},
+			want: []string{"spec.startingDeadlineSeconds", "spec.successfulHistoryLimit", "spec.failedHistoryLimit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &v1alpha1.CronOrderStepSpec{Schedule: "0 2 * * *"}
+			tt.modify(spec)
+			expectFields(t, validateCronSpec(spec), tt.want...)
+		})
+	}
+}
+
+func TestCronValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cos *v1alpha1.CronOrderStep)
+		want   []string
+	}{
+		{name: "valid", modify: func(cos *v1alpha1.CronOrderStep) {}},
+		{
+			name:   "name too long for the OrderSteps",
+			modify: func(cos *v1alpha1.CronOrderStep) { cos.Name = strings.Repeat("a", 50) },
+			want:   []string{"metadata.name"},
+		},
+		{
+			name:   "invalid template",
+			modify: func(cos *v1alpha1.CronOrderStep) { cos.Spec.Template.Spec.Steps[0].Image = "" },
+			want:   []string{"spec.template.spec.steps[0].image"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cos := &v1alpha1.CronOrderStep{
+				ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"},
+				Spec: v1alpha1.CronOrderStepSpec{
+					Schedule: "0 2 * * *",
+					Template: v1alpha1.OrderStepTemplateSpec{
+						Spec: v1alpha1.OrderStepSpec{Steps: []v1alpha1.Step{commandStep("build")}},
+					},
+				},
+			}
+			tt.modify(cos)
+			_, err := NewCronValidator(NewOrderStepValidator(nil, nil)).ValidateCreate(context.Background(), cos)
+			var got []string
+			if status, ok := err.(apierrors.APIStatus); ok && status.Status().Details != nil {
+				for _, cause := range status.Status().Details.Causes {
+					got = append(got, cause.Field)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected errors on %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
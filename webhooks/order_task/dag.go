@@ -0,0 +1,208 @@
+package order_task
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
+)
+
+// validateRunAfter checks runAfter names existing steps without forming a cycle, and that a step of
+// the graph only refers to the steps it runs after, the others may not have run yet.
+func validateRunAfter(ot *v1alpha1.OrderStep) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, step := range ot.Spec.Finally {
+		if len(step.RunAfter) != 0 {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "finally").Index(i).Child("runAfter"),
+				"the finally steps run one after another"))
+		}
+	}
+
+	fldPath := field.NewPath("spec", "steps")
+	indexes := make(map[string]int, len(ot.Spec.Steps))
+	dag := false
+	for i, step := range ot.Spec.Steps {
+		indexes[pod_manager.StepName(i, step)] = i
+		dag = dag || len(step.RunAfter) != 0
+	}
+	if !dag {
+		return allErrs
+	}
+	if ot.Spec.ExecutionMode == v1alpha1.ExecutionModePodPerStep {
+		return append(allErrs, field.Forbidden(fldPath, "runAfter is not supported in podPerStep executionMode"))
+	}
+
+	edges := make([][]int, len(ot.Spec.Steps))
+	for i, step := range ot.Spec.Steps {
+		seen := make(map[string]struct{}, len(step.RunAfter))
+		for j, name := range step.RunAfter {
+			runAfterPath := fldPath.Index(i).Child("runAfter").Index(j)
+			if _, ok := seen[name]; ok {
+				allErrs = append(allErrs, field.Duplicate(runAfterPath, name))
+				continue
+			}
+			seen[name] = struct{}{}
+			k, ok := indexes[name]
+			if !ok {
+				allErrs = append(allErrs, field.NotFound(runAfterPath, name))
+				continue
+			}
+			if k == i {
+				allErrs = append(allErrs, field.Invalid(runAfterPath, name, "a step can not run after itself"))
+				continue
+			}
+			edges[i] = append(edges[i], k)
+		}
+	}
+	if cycle := findCycle(edges); cycle != nil {
+		names := make([]string, 0, len(cycle))
+		for _, i := range cycle {
+			names = append(names, pod_manager.StepName(i, ot.Spec.Steps[i]))
+		}
+		return append(allErrs, field.Invalid(fldPath, strings.Join(names, " -> "), "runAfter forms a cycle"))
+	}
+
+	for i, step := range ot.Spec.Steps {
+		ancestors := make(map[string]struct{})
+		collectAncestors(i, edges, ot.Spec.Steps, ancestors)
+		for _, value := range stepStrings(step) {
+			for _, name := range substitution_util.References(value.value) {
+				if !strings.HasPrefix(name, "steps.") {
+					continue
+				}
+				stepName, _, _ := strings.Cut(strings.TrimPrefix(name, "steps."), ".")
+				if _, ok := ancestors[stepName]; !ok {
+					allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child(value.field), value.value,
+						fmt.Sprintf("%s refers to a step %s does not run after", name, pod_manager.StepName(i, step))))
+				}
+			}
+		}
+	}
+	return allErrs
+}
+
+// findCycle returns the indexes of the steps forming a cycle, nil when there is none.
+func findCycle(edges [][]int) []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(edges))
+	var stack []int
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		states[i] = visiting
+		stack = append(stack, i)
+		for _, j := range edges[i] {
+			switch states[j] {
+			case visiting:
+				for k, s := range stack {
+					if s == j {
+						return append(append([]int{}, stack[k:]...), j)
+					}
+				}
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[i] = visited
+		return nil
+	}
+	for i := range edges {
+		if states[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func collectAncestors(i int, edges [][]int, steps []v1alpha1.Step, ancestors map[string]struct{}) {
+	for _, j := range edges[i] {
+		name := pod_manager.StepName(j, steps[j])
+		if _, ok := ancestors[name]; ok {
+			continue
+		}
+		ancestors[name] = struct{}{}
+		collectAncestors(j, edges, steps, ancestors)
+	}
+}
+
+// validateParallelGroups checks the members of a group are adjacent steps and do not refer to each other.
+func validateParallelGroups(ot *v1alpha1.OrderStep) field.ErrorList {
+	allErrs := field.ErrorList{}
+	groupsPath := field.NewPath("spec", "parallelGroups")
+	configured := make(map[string]struct{}, len(ot.Spec.ParallelGroups))
+	for i, group := range ot.Spec.ParallelGroups {
+		if _, ok := configured[group.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(groupsPath.Index(i).Child("name"), group.Name))
+		}
+		configured[group.Name] = struct{}{}
+		switch group.Completion {
+		case "", v1alpha1.GroupCompletionAll, v1alpha1.GroupCompletionAny:
+		default:
+			allErrs = append(allErrs, field.NotSupported(groupsPath.Index(i).Child("completion"), group.Completion,
+				[]string{string(v1alpha1.GroupCompletionAll), string(v1alpha1.GroupCompletionAny)}))
+		}
+	}
+	for i, step := range ot.Spec.Finally {
+		if len(step.Parallel) != 0 {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "finally").Index(i).Child("parallel"),
+				"the finally steps run one after another"))
+		}
+	}
+
+	fldPath := field.NewPath("spec", "steps")
+	members := make(map[string][]int)
+	for i, step := range ot.Spec.Steps {
+		if len(step.Parallel) == 0 {
+			continue
+		}
+		idxPath := fldPath.Index(i).Child("parallel")
+		if len(step.RunAfter) != 0 {
+			allErrs = append(allErrs, field.Forbidden(idxPath, "can not be set together with runAfter"))
+		}
+		if previous := members[step.Parallel]; len(previous) != 0 && previous[len(previous)-1] != i-1 {
+			allErrs = append(allErrs, field.Invalid(idxPath, step.Parallel, "the members of a group must be adjacent steps"))
+		}
+		members[step.Parallel] = append(members[step.Parallel], i)
+	}
+	if len(members) != 0 && ot.Spec.ExecutionMode == v1alpha1.ExecutionModePodPerStep {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "parallel is not supported in podPerStep executionMode"))
+	}
+	for i, group := range ot.Spec.ParallelGroups {
+		if _, ok := members[group.Name]; !ok {
+			allErrs = append(allErrs, field.Invalid(groupsPath.Index(i).Child("name"), group.Name, "no step belongs to the group"))
+		}
+	}
+
+	for i, step := range ot.Spec.Steps {
+		if len(step.Parallel) == 0 {
+			continue
+		}
+		siblings := make(map[string]struct{})
+		for _, j := range members[step.Parallel] {
+			siblings[pod_manager.StepName(j, ot.Spec.Steps[j])] = struct{}{}
+		}
+		for _, value := range stepStrings(step) {
+			for _, name := range substitution_util.References(value.value) {
+				if !strings.HasPrefix(name, "steps.") {
+					continue
+				}
+				stepName, _, _ := strings.Cut(strings.TrimPrefix(name, "steps."), ".")
+				if _, ok := siblings[stepName]; ok {
+					allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child(value.field), value.value,
+						fmt.Sprintf("%s refers to a step of the same parallel group", name)))
+				}
+			}
+		}
+	}
+	return allErrs
+}
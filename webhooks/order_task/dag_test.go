@@ -0,0 +1,173 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"reflect"
+	"testing"
+)
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges [][]int
+		want  []int
+	}{
+		{name: "no step"},
+		{name: "chain", edges: [][]int{nil, {0}, {1}}},
+		{name: "diamond", edges: [][]int{nil, {0}, {0}, {1, 2}}},
+		{name: "two steps", edges: [][]int{{1}, {0}}, want: []int{0, 1, 0}},
+		{name: "behind a chain", edges: [][]int{nil, {0, 3}, {1}, {2}}, want: []int{1, 3, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findCycle(tt.edges); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateRunAfter(t *testing.T) {
+	runAfter := func(i int, names ...string) func(ot *v1alpha1.OrderStep) {
+		return func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[i].RunAfter = names }
+	}
+	tests := []struct {
+		name   string
+		modify func(ot *v1alpha1.OrderStep)
+		want   []string
+	}{
+		{name: "chain", modify: func(ot *v1alpha1.OrderStep) {}},
+		{name: "fan out", modify: func(ot *v1alpha1.OrderStep) {
+			ot.Spec.Steps[1].RunAfter, ot.Spec.Steps[2].RunAfter = []string{"checkout"}, []string{"checkout"}
+		}},
+		{name: "unknown step", modify: runAfter(1, "build"), want: []string{"spec.steps[1].runAfter[0]"}},
+		{name: "duplicate", modify: runAfter(1, "checkout", "checkout"), want: []string{"spec.steps[1].runAfter[1]"}},
+		{name: "itself", modify: runAfter(1, "lint"), want: []string{"spec.steps[1].runAfter[0]"}},
+		{
+			name: "cycle",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[1].RunAfter, ot.Spec.Steps[2].RunAfter = []string{"test"}, []string{"lint"}
+			},
+			want: []string{"spec.steps"},
+		},
+		{
+			name: "finally step",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Finally = []v1alpha1.Step{commandStep("cleanup")}
+				ot.Spec.Finally[0].RunAfter = []string{"test"}
+			},
+			want: []string{"spec.finally[0].runAfter"},
+		},
+		{
+			name: "podPerStep",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.ExecutionMode = v1alpha1.ExecutionModePodPerStep
+				ot.Spec.Steps[1].RunAfter = []string{"checkout"}
+			},
+			want: []string{"spec.steps"},
+		},
+		{
+			name: "result of a step run after",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[1].RunAfter = []string{"checkout"}
+				ot.Spec.Steps[2].RunAfter = []string{"lint"}
+				ot.Spec.Steps[2].Args = []string{"$(steps.checkout.results.commit)"}
+			},
+		},
+		{
+			name: "result of a step not run after",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[1].RunAfter = []string{"checkout"}
+				ot.Spec.Steps[2].RunAfter = []string{"checkout"}
+				ot.Spec.Steps[2].Args = []string{"$(steps.lint.results.report)"}
+			},
+			want: []string{"spec.steps[2].args"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOrderStep(commandStep("checkout"), commandStep("lint"), commandStep("test"))
+			tt.modify(ot)
+			expectFields(t, validateRunAfter(ot), tt.want...)
+		})
+	}
+}
+
+func TestValidateParallelGroups(t *testing.T) {
+	parallel := func(groups map[int]string) func(ot *v1alpha1.OrderStep) {
+		return func(ot *v1alpha1.OrderStep) {
+			for i, group := range groups {
+				ot.Spec.Steps[i].Parallel = group
+			}
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(ot *v1alpha1.OrderStep)
+		want   []string
+	}{
+		{name: "adjacent", modify: parallel(map[int]string{1: "tests", 2: "tests"})},
+		{
+			name: "configured",
+			modify: func(ot *v1alpha1.OrderStep) {
+				parallel(map[int]string{1: "tests", 2: "tests"})(ot)
+				ot.Spec.ParallelGroups = []v1alpha1.ParallelGroup{{Name: "tests", Completion: v1alpha1.GroupCompletionAny}}
+			},
+		},
+		{name: "not adjacent", modify: parallel(map[int]string{0: "tests", 2: "tests"}), want: []string{"spec.steps[2].parallel"}},
+		{
+			name: "unknown completion",
+			modify: func(ot *v1alpha1.OrderStep) {
+				parallel(map[int]string{1: "tests", 2: "tests"})(ot)
+				ot.Spec.ParallelGroups = []v1alpha1.ParallelGroup{{Name: "tests", Completion: "first"}}
+			},
+			want: []string{"spec.parallelGroups[0].completion"},
+		},
+		{
+			name: "group without member",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.ParallelGroups = []v1alpha1.ParallelGroup{{Name: "tests"}, {Name: "tests"}}
+			},
+			want: []string{"spec.parallelGroups[1].name", "spec.parallelGroups[0].name", "spec.parallelGroups[1].name"},
+		},
+		{
+			name: "with runAfter",
+			modify: func(ot *v1alpha1.OrderStep) {
+				parallel(map[int]string{1: "tests", 2: "tests"})(ot)
+				ot.Spec.Steps[2].RunAfter = []string{"checkout"}
+			},
+			want: []string{"spec.steps[2].parallel"},
+		},
+		{
+			name: "finally step",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Finally = []v1alpha1.Step{commandStep("cleanup")}
+				ot.Spec.Finally[0].Parallel = "cleanup"
+			},
+			want: []string{"spec.finally[0].parallel"},
+		},
+		{
+			name: "podPerStep",
+			modify: func(ot *v1alpha1.OrderStep) {
+				parallel(map[int]string{1: "tests", 2: "tests"})(ot)
+				ot.Spec.ExecutionMode = v1alpha1.ExecutionModePodPerStep
+			},
+			want: []string{"spec.steps"},
+		},
+		{
+			name: "result of a sibling",
+			modify: func(ot *v1alpha1.OrderStep) {
+				parallel(map[int]string{1: "tests", 2: "tests"})(ot)
+				ot.Spec.Steps[2].Args = []string{"$(steps.lint.results.report)"}
+			},
+			want: []string{"spec.steps[2].args"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOrderStep(commandStep("checkout"), commandStep("lint"), commandStep("test"))
+			tt.modify(ot)
+			expectFields(t, validateParallelGroups(ot), tt.want...)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package order_task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	image2 "github.com/daicheng123/ordertask-operator/pkg/image"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"github.com/google/go-containerregistry/pkg/name"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ webhook.CustomDefaulter = &OrderStepDefaulter{}
+
+// OrderStepDefaulter fills in the step fields the PodManager relies on and pins every
+// image to the digest it resolves to, so that re-running an OrderStep executes the same bits.
+type OrderStepDefaulter struct {
+	imageCache *lru.Cache
+}
+
+func NewOrderStepDefaulter(cache *lru.Cache) *OrderStepDefaulter {
+	return &OrderStepDefaulter{
+		imageCache: cache,
+	}
+}
+
+func (d *OrderStepDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	ot, ok := obj.(*v1alpha1.OrderStep)
+	if !ok {
+		return fmt.Errorf("expected an OrderStep but got a %T", obj)
+	}
+
+	if len(ot.Spec.ExecutionMode) == 0 {
+		ot.Spec.ExecutionMode = v1alpha1.ExecutionModePod
+	}
+
+	defaultApprovals(ctx, ot.Spec.Approvals)
+
+	// the spec can not change once created, the images are only pinned then
+	pinDigest := ot.GetAnnotations()[v1alpha1.PinImageDigestAnnotation] != "false"
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation != admissionv1.Create {
+		pinDigest = false
+	}
+	if err := d.defaultSteps(ctx, ot.Spec.Steps, 0, "spec.steps", pinDigest); err != nil {
+		return err
+	}
+	return d.defaultSteps(ctx, ot.Spec.Finally, len(ot.Spec.Steps), "spec.finally", pinDigest)
+}
+
+// defaultSteps defaults steps whose containers start at offset in the pod.
+func (d *OrderStepDefaulter) defaultSteps(ctx context.Context, steps []v1alpha1.Step, offset int, path string, pinDigest bool) error {
+	for i := range steps {
+		step := &steps[i]
+		if len(step.Name) == 0 {
+			step.Name = pod_manager.StepContainerName(offset+i, *step)
+		}
+		if len(step.OnError) == 0 {
+			step.OnError = v1alpha1.OnErrorStop
+		}
+		if len(step.ImagePullPolicy) == 0 {
+			step.ImagePullPolicy = corev1.PullIfNotPresent
+		}
+		// an image taking a param is only known once the pod is built
+		if pinDigest && len(step.Image) != 0 && len(substitution_util.References(step.Image)) == 0 {
+			image, err := d.pinImage(ctx, step.Image)
+			if err != nil {
+				return fmt.Errorf("%s[%d].image: failed to pin %s to a digest: %w", path, i, step.Image, err)
+			}
+			step.Image = image
+		}
+	}
+	return nil
+}
+
+// defaultApprovals records the user adding an approval and when, whatever the request claims.
+// The approvals recorded by an earlier update are left for the validator to compare.
+func defaultApprovals(ctx context.Context, approvals []v1alpha1.Approval) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return
+	}
+	recorded := recordedApprovals(req)
+	now := metav1.Now()
+	for i := range approvals {
+		if recorded[approvals[i].Step] {
+			continue
+		}
+		approvals[i].ApprovedBy = req.UserInfo.Username
+		approvals[i].ApprovedAt = now.DeepCopy()
+	}
+}
+
+// recordedApprovals returns the steps the object being updated already has an approval for,
+// OrderSteps and OrderStepRuns both keep them in spec.approvals.
+func recordedApprovals(req admission.Request) map[string]bool {
+	recorded := make(map[string]bool)
+	if len(req.OldObject.Raw) == 0 {
+		return recorded
+	}
+	old := struct {
+		Spec struct {
+			Approvals []v1alpha1.Approval `json:"approvals"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+		return recorded
+	}
+	for _, approval := range old.Spec.Approvals {
+		recorded[approval.Step] = true
+	}
+	return recorded
+}
+
+// pinImage rewrites a tagged image reference to repo@sha256:..., digests are left untouched.
+func (d *OrderStepDefaulter) pinImage(ctx context.Context, image string) (string, error) {
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := ref.(name.Digest); ok {
+		return image, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, imageLookupTimeout)
+	defer cancel()
+	imageInfo, err := image2.GetImageInfo(ctx, d.imageCache, image)
+	if err != nil {
+		return "", err
+	}
+	return ref.Context().Name() + "@" + imageInfo.Digest.String(), nil
+}
@@ -0,0 +1,56 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func TestOrderStepDefaulter(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		pin       string
+	}{
+		// the images are not looked up, there is no registry to ask
+		{name: "create without pinning", operation: admissionv1.Create, pin: "false"},
+		{name: "update", operation: admissionv1.Update},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOrderStep(commandStep(""), commandStep("test"))
+			ot.Spec.Finally = []v1alpha1.Step{commandStep("")}
+			if len(tt.pin) != 0 {
+				ot.Annotations = map[string]string{v1alpha1.PinImageDigestAnnotation: tt.pin}
+			}
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{Operation: tt.operation},
+			})
+			if err := NewOrderStepDefaulter(nil).Default(ctx, ot); err != nil {
+				t.Fatal(err)
+			}
+
+			if ot.Spec.ExecutionMode != v1alpha1.ExecutionModePod {
+				t.Errorf("expected the pod executionMode, got %q", ot.Spec.ExecutionMode)
+			}
+			for i, name := range []string{"step-1", "test", "step-3"} {
+				step := append(ot.Spec.Steps, ot.Spec.Finally...)[i]
+				if step.Name != name {
+					t.Errorf("expected step %d to be named %s, got %s", i, name, step.Name)
+				}
+				if step.OnError != v1alpha1.OnErrorStop {
+					t.Errorf("expected step %s to stop on error, got %q", step.Name, step.OnError)
+				}
+				if step.ImagePullPolicy != corev1.PullIfNotPresent {
+					t.Errorf("expected step %s to pull if not present, got %q", step.Name, step.ImagePullPolicy)
+				}
+				if step.Image != "alpine:3.18" {
+					t.Errorf("expected the image of step %s to be left alone, got %s", step.Name, step.Image)
+				}
+			}
+		})
+	}
+}
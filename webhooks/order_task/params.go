@@ -0,0 +1,108 @@
+package order_task
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"regexp"
+	"strings"
+)
+
+var paramNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_\-]*$`)
+
+// validateParams checks the declared params, requireDefaults is false for the params of a template
+// which may be bound by the OrderSteps referencing it instead.
+func validateParams(params []v1alpha1.ParamSpec, requireDefaults bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := make(map[string]struct{}, len(params))
+	for i, param := range params {
+		idxPath := fldPath.Index(i)
+		if !paramNameRegexp.MatchString(param.Name) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), param.Name,
+				"must consist of alphanumeric characters, '-' or '_' and start with a letter or '_'"))
+		}
+		if _, ok := names[param.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), param.Name))
+		}
+		names[param.Name] = struct{}{}
+
+		paramType := paramTypeOf(param)
+		if paramType != v1alpha1.ParamTypeString && paramType != v1alpha1.ParamTypeArray {
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("type"), param.Type,
+				[]string{string(v1alpha1.ParamTypeString), string(v1alpha1.ParamTypeArray)}))
+			continue
+		}
+		if param.Default == nil {
+			if requireDefaults {
+				allErrs = append(allErrs, field.Required(idxPath.Child("default"), "a param needs a default to take its value from"))
+			}
+		} else if param.Default.Type != paramType {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("default"), param.Default.Type,
+				fmt.Sprintf("must be of the param type %s", paramType)))
+		}
+	}
+	return allErrs
+}
+
+// validateParamReferences checks every $(params.<name>) used by the steps is declared with the
+// type its usage expects, arrays are only expanded as a whole element of command or args.
+func validateParamReferences(params []v1alpha1.ParamSpec, steps []v1alpha1.Step, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	types := make(map[string]v1alpha1.ParamType, len(params))
+	for _, param := range params {
+		types["params."+param.Name] = paramTypeOf(param)
+	}
+
+	checkString := func(path *field.Path, value string) {
+		for _, name := range substitution_util.References(value) {
+			if !strings.HasPrefix(name, "params.") {
+				continue
+			}
+			paramType, ok := types[name]
+			if !ok {
+				allErrs = append(allErrs, field.Invalid(path, value, fmt.Sprintf("%s is not declared", name)))
+			} else if paramType != v1alpha1.ParamTypeString {
+				allErrs = append(allErrs, field.Invalid(path, value,
+					fmt.Sprintf("array %s can only be used as $(%s[*])", name, name)))
+			}
+		}
+	}
+	checkList := func(path *field.Path, values []string) {
+		for i, value := range values {
+			name, ok := substitution_util.ArrayReference(value)
+			if !ok {
+				checkString(path.Index(i), value)
+				continue
+			}
+			if paramType, declared := types[name]; !declared || paramType != v1alpha1.ParamTypeArray {
+				allErrs = append(allErrs, field.Invalid(path.Index(i), value, fmt.Sprintf("%s is not a declared array param", name)))
+			}
+		}
+	}
+
+	for i, step := range steps {
+		idxPath := fldPath.Index(i)
+		checkString(idxPath.Child("image"), step.Image)
+		checkString(idxPath.Child("script"), step.Script)
+		checkList(idxPath.Child("command"), step.Command)
+		checkList(idxPath.Child("args"), step.Args)
+		for j, env := range step.Env {
+			checkString(idxPath.Child("env").Index(j).Child("value"), env.Value)
+		}
+		for j, expr := range step.When {
+			checkString(idxPath.Child("when").Index(j).Child("input"), expr.Input)
+			for k, value := range expr.Values {
+				checkString(idxPath.Child("when").Index(j).Child("values").Index(k), value)
+			}
+		}
+	}
+	return allErrs
+}
+
+func paramTypeOf(param v1alpha1.ParamSpec) v1alpha1.ParamType {
+	if len(param.Type) == 0 {
+		return v1alpha1.ParamTypeString
+	}
+	return param.Type
+}
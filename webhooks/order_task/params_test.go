@@ -0,0 +1,118 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"testing"
+)
+
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		name            string
+		params          []v1alpha1.ParamSpec
+		requireDefaults bool
+		want            []string
+	}{
+		{
+			name: "string and array",
+			params: []v1alpha1.ParamSpec{
+				{Name: "env", Default: v1alpha1.NewStringParamValue("prod")},
+				{Name: "flags", Type: v1alpha1.ParamTypeArray, Default: v1alpha1.NewArrayParamValue("-v")},
+			},
+			requireDefaults: true,
+		},
+		{
+			name:   "invalid name",
+			params: []v1alpha1.ParamSpec{{Name: "1env", Default: v1alpha1.NewStringParamValue("prod")}},
+			want:   []string{"spec.params[0].name"},
+		},
+		{
+			name: "duplicate name",
+			params: []v1alpha1.ParamSpec{
+				{Name: "env", Default: v1alpha1.NewStringParamValue("prod")},
+				{Name: "env", Default: v1alpha1.NewStringParamValue("staging")},
+			},
+			want: []string{"spec.params[1].name"},
+		},
+		{
+			name:   "unknown type",
+			params: []v1alpha1.ParamSpec{{Name: "env", Type: "object"}},
+			want:   []string{"spec.params[0].type"},
+		},
+		{
+			name:            "missing default",
+			params:          []v1alpha1.ParamSpec{{Name: "env"}},
+			requireDefaults: true,
+			want:            []string{"spec.params[0].default"},
+		},
+		{
+			name:   "missing default of a template param",
+			params: []v1alpha1.ParamSpec{{Name: "env"}},
+		},
+		{
+			name:   "default of another type",
+			params: []v1alpha1.ParamSpec{{Name: "flags", Type: v1alpha1.ParamTypeArray, Default: v1alpha1.NewStringParamValue("-v")}},
+			want:   []string{"spec.params[0].default"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectFields(t, validateParams(tt.params, tt.requireDefaults, field.NewPath("spec", "params")), tt.want...)
+		})
+	}
+}
+
+func TestValidateParamReferences(t *testing.T) {
+	params := []v1alpha1.ParamSpec{
+		{Name: "env", Default: v1alpha1.NewStringParamValue("prod")},
+		{Name: "flags", Type: v1alpha1.ParamTypeArray, Default: v1alpha1.NewArrayParamValue("-v")},
+	}
+	tests := []struct {
+		name   string
+		modify func(step *v1alpha1.Step)
+		want   []string
+	}{
+		{
+			name: "declared",
+			modify: func(step *v1alpha1.Step) {
+				step.Args = []string{"--env=$(params.env)", "$(params.flags[*])"}
+				step.Script = "echo $(params.env)"
+			},
+		},
+		{
+			name:   "not declared",
+			modify: func(step *v1alpha1.Step) { step.Image = "registry/$(params.region)/app" },
+			want:   []string{"spec.steps[0].image"},
+		},
+		{
+			name:   "array as a string",
+			modify: func(step *v1alpha1.Step) { step.Args = []string{"--flags=$(params.flags)"} },
+			want:   []string{"spec.steps[0].args[0]"},
+		},
+		{
+			name:   "string as an array",
+			modify: func(step *v1alpha1.Step) { step.Command = []string{"deploy", "$(params.env[*])"} },
+			want:   []string{"spec.steps[0].command[1]"},
+		},
+		{
+			name: "in the when expressions",
+			modify: func(step *v1alpha1.Step) {
+				step.When = []v1alpha1.WhenExpression{{Input: "$(params.region)", Operator: v1alpha1.WhenOperatorIn, Values: []string{"$(params.flags)"}}}
+			},
+			want: []string{"spec.steps[0].when[0].input", "spec.steps[0].when[0].values[0]"},
+		},
+		{
+			name: "other variables are left alone",
+			modify: func(step *v1alpha1.Step) {
+				step.Args = []string{"$(steps.build.results.digest)", "$(workspaces.src.path)"}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := commandStep("deploy")
+			tt.modify(&step)
+			expectFields(t, validateParamReferences(params, []v1alpha1.Step{step}, field.NewPath("spec", "steps")), tt.want...)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validatePodTemplate checks the podTemplate fields the api server would reject once merged into the pod.
+func validatePodTemplate(template *v1alpha1.PodTemplate, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if template == nil {
+		return allErrs
+	}
+	for key, value := range template.NodeSelector {
+		for _, msg := range validation.IsQualifiedName(key) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("nodeSelector"), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("nodeSelector").Key(key), value, msg))
+		}
+	}
+	for i, toleration := range template.Tolerations {
+		switch toleration.Operator {
+		case "", corev1.TolerationOpEqual, corev1.TolerationOpExists:
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("tolerations").Index(i).Child("operator"), toleration.Operator,
+				[]string{string(corev1.TolerationOpEqual), string(corev1.TolerationOpExists)}))
+		}
+	}
+	runtimeClassName := ""
+	if template.RuntimeClassName != nil {
+		runtimeClassName = *template.RuntimeClassName
+	}
+	for _, name := range []struct{ child, value string }{
+		{"serviceAccountName", template.ServiceAccountName},
+		{"priorityClassName", template.PriorityClassName},
+		{"runtimeClassName", runtimeClassName},
+	} {
+		if len(name.value) == 0 {
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(name.value) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child(name.child), name.value, msg))
+		}
+	}
+	for i, secret := range template.ImagePullSecrets {
+		if len(secret.Name) == 0 {
+			allErrs = append(allErrs, field.Required(fldPath.Child("imagePullSecrets").Index(i).Child("name"), ""))
+		}
+	}
+	return allErrs
+}
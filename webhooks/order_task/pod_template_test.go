@@ -0,0 +1,59 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	"testing"
+)
+
+func TestValidatePodTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template *v1alpha1.PodTemplate
+		want     []string
+	}{
+		{name: "none"},
+		{
+			name: "valid",
+			template: &v1alpha1.PodTemplate{
+				NodeSelector:       map[string]string{"kubernetes.io/arch": "amd64"},
+				Tolerations:        []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+				ServiceAccountName: "builder",
+				RuntimeClassName:   pointer.String("gvisor"),
+				ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+			},
+		},
+		{
+			name:     "invalid nodeSelector key",
+			template: &v1alpha1.PodTemplate{NodeSelector: map[string]string{"arch!": "amd64"}},
+			want:     []string{"spec.podTemplate.nodeSelector"},
+		},
+		{
+			name:     "invalid nodeSelector value",
+			template: &v1alpha1.PodTemplate{NodeSelector: map[string]string{"arch": "amd 64"}},
+			want:     []string{"spec.podTemplate.nodeSelector[arch]"},
+		},
+		{
+			name:     "unknown toleration operator",
+			template: &v1alpha1.PodTemplate{Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: "In"}}},
+			want:     []string{"spec.podTemplate.tolerations[0].operator"},
+		},
+		{
+			name:     "invalid names",
+			template: &v1alpha1.PodTemplate{ServiceAccountName: "Builder", PriorityClassName: "high_priority", RuntimeClassName: pointer.String("")},
+			want:     []string{"spec.podTemplate.serviceAccountName", "spec.podTemplate.priorityClassName"},
+		},
+		{
+			name:     "unnamed pull secret",
+			template: &v1alpha1.PodTemplate{ImagePullSecrets: []corev1.LocalObjectReference{{}}},
+			want:     []string{"spec.podTemplate.imagePullSecrets[0].name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectFields(t, validatePodTemplate(tt.template, field.NewPath("spec", "podTemplate")), tt.want...)
+		})
+	}
+}
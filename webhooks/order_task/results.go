@@ -0,0 +1,100 @@
+package order_task
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
+)
+
+// validateResults checks the declared results and that a step only refers to its own result
+// paths and to results declared by the steps running before it.
+func validateResults(spec *v1alpha1.OrderStepSpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	declared := make(map[string]struct{})
+
+	lists := []struct {
+		steps   []v1alpha1.Step
+		offset  int
+		fldPath *field.Path
+	}{
+		{spec.Steps, 0, field.NewPath("spec", "steps")},
+		{spec.Finally, len(spec.Steps), field.NewPath("spec", "finally")},
+	}
+	for _, list := range lists {
+		for i, step := range list.steps {
+			idxPath := list.fldPath.Index(i)
+			stepName := pod_manager.StepName(list.offset+i, step)
+
+			own := make(map[string]struct{}, len(step.Results))
+			for j, result := range step.Results {
+				if !paramNameRegexp.MatchString(result.Name) {
+					allErrs = append(allErrs, field.Invalid(idxPath.Child("results").Index(j).Child("name"), result.Name,
+						"must consist of alphanumeric characters, '-' or '_' and start with a letter or '_'"))
+				}
+				if _, ok := own[result.Name]; ok {
+					allErrs = append(allErrs, field.Duplicate(idxPath.Child("results").Index(j).Child("name"), result.Name))
+				}
+				own[result.Name] = struct{}{}
+			}
+
+			check := func(path *field.Path, value string) {
+				for _, name := range substitution_util.References(value) {
+					switch {
+					case strings.HasPrefix(name, "results."):
+						result := strings.TrimSuffix(strings.TrimPrefix(name, "results."), ".path")
+						if _, ok := own[result]; !ok || !strings.HasSuffix(name, ".path") {
+							allErrs = append(allErrs, field.Invalid(path, value,
+								fmt.Sprintf("%s is not the path of a result declared by the step", name)))
+						}
+					case strings.HasPrefix(name, "steps.") && strings.Contains(name, ".results."):
+						// the script is written before any step ran, only the entrypoint resolves results
+						if path.String() == idxPath.Child("script").String() {
+							allErrs = append(allErrs, field.Invalid(path, "<script>",
+								fmt.Sprintf("%s can not be used in a script, pass it through an env var", name)))
+						} else if _, ok := declared[name]; !ok {
+							allErrs = append(allErrs, field.Invalid(path, value,
+								fmt.Sprintf("%s is not a result declared by an earlier step", name)))
+						}
+					}
+				}
+			}
+			for _, value := range stepStrings(step) {
+				check(idxPath.Child(value.field), value.value)
+			}
+
+			for result := range own {
+				declared["steps."+stepName+".results."+result] = struct{}{}
+			}
+		}
+	}
+	return allErrs
+}
+
+type stepString struct {
+	field string
+	value string
+}
+
+// stepStrings returns the fields of a step variables can be substituted in.
+func stepStrings(step v1alpha1.Step) []stepString {
+	values := []stepString{{"image", step.Image}, {"script", step.Script}}
+	for _, v := range step.Command {
+		values = append(values, stepString{"command", v})
+	}
+	for _, v := range step.Args {
+		values = append(values, stepString{"args", v})
+	}
+	for _, env := range step.Env {
+		values = append(values, stepString{"env", env.Value})
+	}
+	for _, expr := range step.When {
+		values = append(values, stepString{"when", expr.Input})
+		for _, v := range expr.Values {
+			values = append(values, stepString{"when", v})
+		}
+	}
+	return values
+}
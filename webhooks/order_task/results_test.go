@@ -0,0 +1,73 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestValidateResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(ot *v1alpha1.OrderStep)
+		want   []string
+	}{
+		{
+			name: "written and used",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Results = []v1alpha1.StepResult{{Name: "digest"}}
+				ot.Spec.Steps[0].Args = []string{"--digest-file", "$(results.digest.path)"}
+				ot.Spec.Steps[1].Args = []string{"$(steps.compile.results.digest)"}
+			},
+		},
+		{
+			name: "used by a finally step",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Results = []v1alpha1.StepResult{{Name: "digest"}}
+				ot.Spec.Finally = []v1alpha1.Step{commandStep("notify")}
+				ot.Spec.Finally[0].Env = []corev1.EnvVar{{Name: "DIGEST", Value: "$(steps.compile.results.digest)"}}
+			},
+		},
+		{
+			name:   "invalid name",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Results = []v1alpha1.StepResult{{Name: "image.digest"}} },
+			want:   []string{"spec.steps[0].results[0].name"},
+		},
+		{
+			name: "duplicate name",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Results = []v1alpha1.StepResult{{Name: "digest"}, {Name: "digest"}}
+			},
+			want: []string{"spec.steps[0].results[1].name"},
+		},
+		{
+			name:   "path of an undeclared result",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Args = []string{"$(results.digest.path)"} },
+			want:   []string{"spec.steps[0].args"},
+		},
+		{
+			name: "result of a later step",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Args = []string{"$(steps.test.results.report)"}
+				ot.Spec.Steps[1].Results = []v1alpha1.StepResult{{Name: "report"}}
+			},
+			want: []string{"spec.steps[0].args"},
+		},
+		{
+			name: "result in a script",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Results = []v1alpha1.StepResult{{Name: "digest"}}
+				ot.Spec.Steps[1].Command = nil
+				ot.Spec.Steps[1].Script = "echo $(steps.compile.results.digest)"
+			},
+			want: []string{"spec.steps[1].script"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOrderStep(commandStep("compile"), commandStep("test"))
+			tt.modify(ot)
+			expectFields(t, validateResults(&ot.Spec), tt.want...)
+		})
+	}
+}
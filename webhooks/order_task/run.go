@@ -0,0 +1,171 @@
+package order_task
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var (
+	_ webhook.CustomDefaulter = &RunDefaulter{}
+	_ webhook.CustomValidator = &RunValidator{}
+)
+
+// RunDefaulter records who approved the approval steps of an OrderStepRun.
+type RunDefaulter struct{}
+
+func NewRunDefaulter() *RunDefaulter {
+	return &RunDefaulter{}
+}
+
+func (d *RunDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	run, ok := obj.(*v1alpha1.OrderStepRun)
+	if !ok {
+		return fmt.Errorf("expected an OrderStepRun but got a %T", obj)
+	}
+	defaultApprovals(ctx, run.Spec.Approvals)
+	return nil
+}
+
+// RunValidator rejects OrderStepRuns whose OrderStep can not be resolved with the params they bind,
+// the steps themselves were validated with the OrderStep.
+type RunValidator struct {
+	*OrderStepValidator
+}
+
+func NewRunValidator(validator *OrderStepValidator) *RunValidator {
+	return &RunValidator{OrderStepValidator: validator}
+}
+
+func (v *RunValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validateRun(ctx, obj, nil)
+}
+
+func (v *RunValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*v1alpha1.OrderStepRun)
+	if !ok {
+		return nil, fmt.Errorf("expected an OrderStepRun but got a %T", oldObj)
+	}
+	return v.validateRun(ctx, newObj, old)
+}
+
+func (v *RunValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RunValidator) validateRun(ctx context.Context, obj runtime.Object, old *v1alpha1.OrderStepRun) (admission.Warnings, error) {
+	run, ok := obj.(*v1alpha1.OrderStepRun)
+	if !ok {
+		return nil, fmt.Errorf("expected an OrderStepRun but got a %T", obj)
+	}
+
+	allErrs := validateRunSpec(run, old)
+	if len(allErrs) == 0 {
+		spec, err := pod_manager.ResolveRunSpec(ctx, v.reader, run)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "orderStepRef"), run.Spec.OrderStepRef.Name, err.Error()))
+		} else {
+			// a generated name is not known yet
+			if len(run.GetName()) != 0 {
+				allErrs = append(allErrs, validateRunNames(run, spec)...)
+			}
+			// the status and approvals are checked like the ones of the OrderStep the run executes
+			ot := pod_manager.RunOrderStep(run, spec)
+			var oldOrderStep *v1alpha1.OrderStep
+			if old != nil {
+				oldOrderStep = &v1alpha1.OrderStep{Spec: v1alpha1.OrderStepSpec{Status: old.Spec.Status, Approvals: old.Spec.Approvals}}
+			}
+			allErrs = append(allErrs, validateSpecStatus(ot, oldOrderStep)...)
+			allErrs = append(allErrs, validateApprovals(ctx, ot, oldOrderStep)...)
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil, nil
+	}
+	return nil, apierrors.NewInvalid(
+		schema.GroupKind{Group: v1alpha1.OrderTaskGroup, Kind: v1alpha1.OrderStepRunResourceKind},
+		run.GetName(), allErrs)
+}
+
+// validateRunSpec checks the reference and the bindings, what a run executes can not change once created.
+func validateRunSpec(run, old *v1alpha1.OrderStepRun) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec")
+	if len(run.Spec.OrderStepRef.Name) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("orderStepRef", "name"), ""))
+	}
+	names := make(map[string]struct{}, len(run.Spec.Params))
+	for i, binding := range run.Spec.Params {
+		if _, ok := names[binding.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("params").Index(i).Child("name"), binding.Name))
+		}
+		names[binding.Name] = struct{}{}
+	}
+	if old == nil {
+		return allErrs
+	}
+	if run.Spec.OrderStepRef != old.Spec.OrderStepRef {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("orderStepRef"), "can not be changed"))
+	}
+	if !equality.Semantic.DeepEqual(run.Spec.Params, old.Spec.Params) {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("params"), "can not be changed"))
+	}
+	return allErrs
+}
+
+// validateRunNames checks the names of the objects created for the run, they start with a base name
+// of their own and may be longer than the ones of its OrderStep.
+func validateRunNames(run *v1alpha1.OrderStepRun, spec *v1alpha1.OrderStepSpec) field.ErrorList {
+	fldPath := field.NewPath("metadata", "name")
+	baseName := pod_manager.GenerateRunBaseName(run.GetName())
+	allErrs := validateName(baseName, run.GetName(), fldPath)
+
+	steps := append(append([]v1alpha1.Step{}, spec.Steps...), spec.Finally...)
+	for i, step := range steps {
+		if pod_manager.StepContainerName(i, step) == pod_manager.InitContainerName(baseName) {
+			allErrs = append(allErrs, field.Invalid(fldPath, run.GetName(),
+				fmt.Sprintf("init container name clashes with step %s", pod_manager.StepName(i, step))))
+		}
+		if spec.ExecutionMode != v1alpha1.ExecutionModePodPerStep {
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(pod_manager.StepPodName(baseName, i, step)) {
+			allErrs = append(allErrs, field.Invalid(fldPath, run.GetName(),
+				fmt.Sprintf("pod name of step %s %s", pod_manager.StepName(i, step), msg)))
+		}
+	}
+	for _, ws := range spec.Workspaces {
+		if ws.VolumeClaimTemplate == nil {
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(pod_manager.WorkspaceClaimName(baseName, ws.Name)) {
+			allErrs = append(allErrs, field.Invalid(fldPath, run.GetName(),
+				fmt.Sprintf("claim name of workspace %s %s", ws.Name, msg)))
+		}
+	}
+	return allErrs
+}
+
+// validateRunPolicy checks the policy and how many runs of the OrderStep are kept.
+func validateRunPolicy(spec *v1alpha1.OrderStepSpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch spec.RunPolicy {
+	case "", v1alpha1.RunPolicyAuto, v1alpha1.RunPolicyManual:
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("spec", "runPolicy"), spec.RunPolicy,
+			[]string{string(v1alpha1.RunPolicyAuto), string(v1alpha1.RunPolicyManual)}))
+	}
+	if limit := spec.RunHistoryLimit; limit != nil && *limit < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "runHistoryLimit"), *limit, "must be greater than or equal to 0"))
+	}
+	return allErrs
+}
@@ -0,0 +1,168 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+)
+
+// orderStepReader serves the OrderSteps of the default namespace.
+type orderStepReader struct {
+	client.Reader
+	orderSteps map[string]*v1alpha1.OrderStep
+}
+
+func (r *orderStepReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	ot, ok := r.orderSteps[key.Name]
+	if !ok || key.Namespace != "default" {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "ordersteps"}, key.Name)
+	}
+	ot.DeepCopyInto(obj.(*v1alpha1.OrderStep))
+	return nil
+}
+
+func newRun(name string, params ...v1alpha1.ParamBinding) *v1alpha1.OrderStepRun {
+	return &v1alpha1.OrderStepRun{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1alpha1.OrderStepRunSpec{OrderStepRef: v1alpha1.OrderStepRef{Name: "build"}, Params: params},
+	}
+}
+
+func TestValidateRunSpec(t *testing.T) {
+	env := v1alpha1.ParamBinding{Name: "env", Value: *v1alpha1.NewStringParamValue("prod")}
+	tests := []struct {
+		name   string
+		old    *v1alpha1.OrderStepRun
+		modify func(run *v1alpha1.OrderStepRun)
+		want   []string
+	}{
+		{name: "valid", modify: func(run *v1alpha1.OrderStepRun) {}},
+		{name: "no reference", modify: func(run *v1alpha1.OrderStepRun) { run.Spec.OrderStepRef.Name = "" }, want: []string{"spec.orderStepRef.name"}},
+		{
+			name:   "param bound twice",
+			modify: func(run *v1alpha1.OrderStepRun) { run.Spec.Params = append(run.Spec.Params, env) },
+			want:   []string{"spec.params[1].name"},
+		},
+		{
+			name:   "cancelled",
+			old:    newRun("build-1", env),
+			modify: func(run *v1alpha1.OrderStepRun) { run.Spec.Status = v1alpha1.OrderStepSpecStatusCancelled },
+		},
+		{
+			name:   "reference changed",
+			old:    newRun("build-1", env),
+			modify: func(run *v1alpha1.OrderStepRun) { run.Spec.OrderStepRef.Name = "deploy" },
+			want:   []string{"spec.orderStepRef"},
+		},
+		{
+			name:   "params changed",
+			old:    newRun("build-1", env),
+			modify: func(run *v1alpha1.OrderStepRun) { run.Spec.Params = nil },
+			want:   []string{"spec.params"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := newRun("build-1", env)
+			tt.modify(run)
+			expectFields(t, validateRunSpec(run, tt.old), tt.want...)
+		})
+	}
+}
+
+func TestValidateRunNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		runName string
+		modify  func(spec *v1alpha1.OrderStepSpec)
+		want    []string
+	}{
+		{name: "valid", runName: "build-1", modify: func(spec *v1alpha1.OrderStepSpec) {}},
+		{name: "too long", runName: strings.Repeat("a", 60), modify: func(spec *v1alpha1.OrderStepSpec) {}, want: []string{"metadata.name"}},
+		{
+			name:    "step named like the init container",
+			runName: "build-1",
+			modify: func(spec *v1alpha1.OrderStepSpec) {
+				spec.Steps[0].Name = pod_manager.InitContainerName(pod_manager.GenerateRunBaseName("build-1"))
+			},
+			want: []string{"metadata.name"},
+		},
+		{
+			// the init container name is too long as well
+			name:    "step pod name too long",
+			runName: strings.Repeat("a", 200),
+			modify: func(spec *v1alpha1.OrderStepSpec) {
+				spec.ExecutionMode = v1alpha1.ExecutionModePodPerStep
+				spec.Steps[0].Name = strings.Repeat("b", 63)
+			},
+			want: []string{"metadata.name", "metadata.name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &v1alpha1.OrderStepSpec{Steps: []v1alpha1.Step{commandStep("compile")}}
+			tt.modify(spec)
+			expectFields(t, validateRunNames(newRun(tt.runName), spec), tt.want...)
+		})
+	}
+}
+
+func TestValidateRunPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		spec v1alpha1.OrderStepSpec
+		want []string
+	}{
+		{name: "auto"},
+		{name: "manual", spec: v1alpha1.OrderStepSpec{RunPolicy: v1alpha1.RunPolicyManual, RunHistoryLimit: pointer.Int32(0)}},
+		{name: "unknown", spec: v1alpha1.OrderStepSpec{RunPolicy: "Always"}, want: []string{"spec.runPolicy"}},
+		{name: "negative history", spec: v1alpha1.OrderStepSpec{RunHistoryLimit: pointer.Int32(-1)}, want: []string{"spec.runHistoryLimit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectFields(t, validateRunPolicy(&tt.spec), tt.want...)
+		})
+	}
+}
+
+func TestRunValidator(t *testing.T) {
+	definition := newOrderStep(commandStep("compile"))
+	definition.Spec.RunPolicy = v1alpha1.RunPolicyManual
+	definition.Spec.Params = []v1alpha1.ParamSpec{{Name: "env"}}
+	v := NewRunValidator(NewOrderStepValidator(nil, &orderStepReader{orderSteps: map[string]*v1alpha1.OrderStep{"build": definition}}))
+	tests := []struct {
+		name    string
+		run     *v1alpha1.OrderStepRun
+		wantErr bool
+	}{
+		{name: "bound", run: newRun("build-1", v1alpha1.ParamBinding{Name: "env", Value: *v1alpha1.NewStringParamValue("prod")})},
+		{name: "unbound", run: newRun("build-1"), wantErr: true},
+		{
+			name: "unknown OrderStep",
+			run: func() *v1alpha1.OrderStepRun {
+				run := newRun("build-1")
+				run.Spec.OrderStepRef.Name = "deploy"
+				return run
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.ValidateCreate(context.Background(), tt.run)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected an error: %t, got %v", tt.wantErr, err)
+			}
+			if err != nil && !apierrors.IsInvalid(err) {
+				t.Errorf("expected the run to be invalid, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package order_task
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// validateTemplateRef checks an OrderStep referencing a template leaves what it takes from it out.
+func validateTemplateRef(spec *v1alpha1.OrderStepSpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec", "templateRef")
+	ref := spec.TemplateRef
+	if len(ref.Name) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+	}
+	switch ref.Kind {
+	case "", v1alpha1.TemplateKindNamespaced, v1alpha1.TemplateKindCluster:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("kind"), ref.Kind,
+			[]string{string(v1alpha1.TemplateKindNamespaced), string(v1alpha1.TemplateKindCluster)}))
+	}
+	names := make(map[string]struct{}, len(ref.Params))
+	for i, binding := range ref.Params {
+		if _, ok := names[binding.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("params").Index(i).Child("name"), binding.Name))
+		}
+		names[binding.Name] = struct{}{}
+	}
+
+	taken := map[string]bool{
+		"steps":          len(spec.Steps) != 0,
+		"finally":        len(spec.Finally) != 0,
+		"params":         len(spec.Params) != 0,
+		"parallelGroups": len(spec.ParallelGroups) != 0,
+		"workspaces":     len(spec.Workspaces) != 0,
+	}
+	for _, name := range []string{"steps", "finally", "params", "parallelGroups", "workspaces"} {
+		if taken[name] {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", name), "taken from the templateRef"))
+		}
+	}
+	return allErrs
+}
+
+var (
+	_ webhook.CustomDefaulter = &TemplateDefaulter{}
+	_ webhook.CustomValidator = &TemplateValidator{}
+)
+
+// TemplateDefaulter defaults the steps of OrderStepTemplates and ClusterOrderStepTemplates
+// the same way as the ones of an OrderStep.
+type TemplateDefaulter struct {
+	*OrderStepDefaulter
+}
+
+func NewTemplateDefaulter(defaulter *OrderStepDefaulter) *TemplateDefaulter {
+	return &TemplateDefaulter{OrderStepDefaulter: defaulter}
+}
+
+func (d *TemplateDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	meta, _, spec, err := templateOf(obj)
+	if err != nil {
+		return err
+	}
+	// a template may change, the images it is updated with are pinned as well
+	pinDigest := meta.GetAnnotations()[v1alpha1.PinImageDigestAnnotation] != "false"
+	if err = d.defaultSteps(ctx, spec.Steps, 0, "spec.steps", pinDigest); err != nil {
+		return err
+	}
+	return d.defaultSteps(ctx, spec.Finally, len(spec.Steps), "spec.finally", pinDigest)
+}
+
+// TemplateValidator validates the steps of OrderStepTemplates and ClusterOrderStepTemplates like the
+// ones of an OrderStep, what depends on the OrderStep itself is checked once it references the template.
+type TemplateValidator struct {
+	*OrderStepValidator
+}
+
+func NewTemplateValidator(validator *OrderStepValidator) *TemplateValidator {
+	return &TemplateValidator{OrderStepValidator: validator}
+}
+
+func (v *TemplateValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validateTemplate(ctx, obj)
+}
+
+func (v *TemplateValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validateTemplate(ctx, newObj)
+}
+
+func (v *TemplateValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *TemplateValidator) validateTemplate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	meta, kind, spec, err := templateOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	ot := &v1alpha1.OrderStep{
+		ObjectMeta: metav1.ObjectMeta{Name: meta.GetName(), Namespace: meta.GetNamespace()},
+		Spec: v1alpha1.OrderStepSpec{
+			Steps:          spec.Steps,
+			Finally:        spec.Finally,
+			Params:         spec.Params,
+			ParallelGroups: spec.ParallelGroups,
+			Workspaces:     spec.Workspaces,
+			PodTemplate:    spec.PodTemplate,
+			ActiveDeadline: spec.ActiveDeadline,
+		},
+	}
+	allErrs := v.validateSpec(ctx, ot, false)
+	if len(allErrs) == 0 {
+		return nil, nil
+	}
+	return nil, apierrors.NewInvalid(
+		schema.GroupKind{Group: v1alpha1.OrderTaskGroup, Kind: string(kind)},
+		meta.GetName(), allErrs)
+}
+
+func templateOf(obj runtime.Object) (metav1.Object, v1alpha1.TemplateKind, *v1alpha1.StepTemplateSpec, error) {
+	switch template := obj.(type) {
+	case *v1alpha1.OrderStepTemplate:
+		return template, v1alpha1.TemplateKindNamespaced, &template.Spec, nil
+	case *v1alpha1.ClusterOrderStepTemplate:
+		return template, v1alpha1.TemplateKindCluster, &template.Spec, nil
+	default:
+		return nil, "", nil, fmt.Errorf("expected an OrderStepTemplate or a ClusterOrderStepTemplate but got a %T", obj)
+	}
+}
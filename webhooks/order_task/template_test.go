@@ -0,0 +1,111 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestValidateTemplateRef(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(spec *v1alpha1.OrderStepSpec)
+		want   []string
+	}{
+		{name: "valid", modify: func(spec *v1alpha1.OrderStepSpec) {}},
+		{
+			name:   "cluster template",
+			modify: func(spec *v1alpha1.OrderStepSpec) { spec.TemplateRef.Kind = v1alpha1.TemplateKindCluster },
+		},
+		{name: "no name", modify: func(spec *v1alpha1.OrderStepSpec) { spec.TemplateRef.Name = "" }, want: []string{"spec.templateRef.name"}},
+		{name: "unknown kind", modify: func(spec *v1alpha1.OrderStepSpec) { spec.TemplateRef.Kind = "Pipeline" }, want: []string{"spec.templateRef.kind"}},
+		{
+			name: "param bound twice",
+			modify: func(spec *v1alpha1.OrderStepSpec) {
+				spec.TemplateRef.Params = append(spec.TemplateRef.Params, v1alpha1.ParamBinding{Name: "env", Value: *v1alpha1.NewStringParamValue("staging")})
+			},
+			want: []string{"spec.templateRef.params[1].name"},
+		},
+		{
+			name: "fields of the template",
+			modify: func(spec *v1alpha1.OrderStepSpec) {
+				spec.Steps = []v1alpha1.Step{commandStep("build")}
+				spec.Params = []v1alpha1.ParamSpec{{Name: "env"}}
+			},
+			want: []string{"spec.steps", "spec.params"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &v1alpha1.OrderStepSpec{TemplateRef: &v1alpha1.TemplateRef{
+				Name:   "build",
+				Params: []v1alpha1.ParamBinding{{Name: "env", Value: *v1alpha1.NewStringParamValue("prod")}},
+			}}
+			tt.modify(spec)
+			expectFields(t, validateTemplateRef(spec), tt.want...)
+		})
+	}
+}
+
+func TestValidateTemplateRefUpdate(t *testing.T) {
+	approvedAt := metav1.NewTime(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	snapshot := &v1alpha1.OrderStepSpec{Steps: []v1alpha1.Step{commandStep("build"), approvalStep("release")}}
+	tests := []struct {
+		name      string
+		runPolicy v1alpha1.RunPolicy
+		resolved  *v1alpha1.OrderStepSpec
+		create    bool
+		modify    func(ot *v1alpha1.OrderStep)
+		want      []string
+	}{
+		{name: "created", create: true, modify: func(ot *v1alpha1.OrderStep) {}, want: []string{"spec.templateRef"}},
+		{
+			name:   "cancelled before resolved",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Status = v1alpha1.OrderStepSpecStatusCancelled },
+		},
+		{
+			name:     "paused once resolved",
+			resolved: snapshot,
+			modify:   func(ot *v1alpha1.OrderStep) { ot.Spec.Status = v1alpha1.OrderStepSpecStatusPaused },
+		},
+		{
+			name:     "approved from the snapshot",
+			resolved: snapshot,
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Approvals = []v1alpha1.Approval{{Step: "release", ApprovedBy: "bob", ApprovedAt: &approvedAt}}
+			},
+		},
+		{
+			name:     "approval of a step not in the snapshot",
+			resolved: snapshot,
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Approvals = []v1alpha1.Approval{{Step: "build", ApprovedBy: "bob", ApprovedAt: &approvedAt}}
+			},
+			want: []string{"spec.approvals[0].step"},
+		},
+		{
+			name:      "definition edited",
+			runPolicy: v1alpha1.RunPolicyManual,
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.TemplateRef.Params = []v1alpha1.ParamBinding{{Name: "env", Value: *v1alpha1.NewStringParamValue("prod")}}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the template has been deleted since the OrderStep was created
+			v := NewOrderStepValidator(nil, &orderStepReader{})
+			old := newOrderStep()
+			old.Spec.TemplateRef = &v1alpha1.TemplateRef{Name: "build"}
+			old.Spec.RunPolicy = tt.runPolicy
+			old.Status.ResolvedSpec = tt.resolved
+			ot := old.DeepCopy()
+			tt.modify(ot)
+			if tt.create {
+				old = nil
+			}
+			expectFields(t, v.validateOrderStep(userContext(t, "bob", old), ot, old), tt.want...)
+		})
+	}
+}
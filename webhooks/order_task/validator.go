@@ -0,0 +1,347 @@
+package order_task
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	image2 "github.com/daicheng123/ordertask-operator/pkg/image"
+	"github.com/daicheng123/ordertask-operator/pkg/termination"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"time"
+)
+
+var _ webhook.CustomValidator = &OrderStepValidator{}
+
+// OrderStepValidator rejects OrderSteps whose pod could not be built by the PodManager.
+type OrderStepValidator struct {
+	imageCache *lru.Cache
+	// reader looks up the templates the OrderSteps refer to
+	reader client.Reader
+}
+
+func NewOrderStepValidator(cache *lru.Cache, reader client.Reader) *OrderStepValidator {
+	return &OrderStepValidator{
+		imageCache: cache,
+		reader:     reader,
+	}
+}
+
+func (v *OrderStepValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj, nil)
+}
+
+func (v *OrderStepValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*v1alpha1.OrderStep)
+	if !ok {
+		return nil, fmt.Errorf("expected an OrderStep but got a %T", oldObj)
+	}
+	return v.validate(ctx, newObj, old)
+}
+
+func (v *OrderStepValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the OrderStep, old is nil on create.
+func (v *OrderStepValidator) validate(ctx context.Context, obj runtime.Object, old *v1alpha1.OrderStep) (admission.Warnings, error) {
+	ot, ok := obj.(*v1alpha1.OrderStep)
+	if !ok {
+		return nil, fmt.Errorf("expected an OrderStep but got a %T", obj)
+	}
+
+	allErrs := validateName(pod_manager.GenerateBaseName(ot.GetName()), ot.GetName(), field.NewPath("metadata", "name"))
+	allErrs = append(allErrs, v.validateOrderStep(ctx, ot, old)...)
+	if len(allErrs) == 0 {
+		return nil, nil
+	}
+	return nil, apierrors.NewInvalid(
+		schema.GroupKind{Group: v1alpha1.OrderTaskGroup, Kind: v1alpha1.OrderTaskResourceKind},
+		ot.GetName(), allErrs)
+}
+
+// validateOrderStep checks the spec of the OrderStep, old is nil on create.
+func (v *OrderStepValidator) validateOrderStep(ctx context.Context, ot, old *v1alpha1.OrderStep) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if old != nil {
+		allErrs = append(allErrs, validateSpecUpdate(ot, old)...)
+	}
+	// the rest of the spec of a running OrderStep was validated on create, the images are not looked up
+	// again, a definition is checked again on every change
+	validateAll := old == nil || old.Spec.RunPolicy == v1alpha1.RunPolicyManual
+	if ot.Spec.TemplateRef != nil {
+		errs := validateTemplateRef(&ot.Spec)
+		switch {
+		case len(errs) != 0:
+		case old == nil || ot.Status.ResolvedSpec != nil:
+			// the template is only looked up on create, its snapshot is validated afterwards
+			spec, err := pod_manager.ResolveSpec(ctx, v.reader, ot)
+			if err != nil {
+				errs = append(errs, field.Invalid(field.NewPath("spec", "templateRef"), ot.Spec.TemplateRef.Name, err.Error()))
+			} else {
+				// the steps of the template are validated as part of the OrderStep
+				ot = ot.DeepCopy()
+				ot.Spec = *spec
+			}
+		default:
+			// the template may be gone since, the steps are checked once resolved for a run
+			validateAll = false
+		}
+		if len(errs) != 0 {
+			return append(allErrs, errs...)
+		}
+	}
+	if validateAll {
+		// the params of a definition may be left for its runs to bind
+		allErrs = append(allErrs, v.validateSpec(ctx, ot, ot.Spec.RunPolicy != v1alpha1.RunPolicyManual)...)
+		allErrs = append(allErrs, validateExecutionMode(ot)...)
+		allErrs = append(allErrs, validateRunPolicy(&ot.Spec)...)
+		if ttl := ot.Spec.TTLSecondsAfterFinished; ttl != nil && *ttl < 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "ttlSecondsAfterFinished"), *ttl, "must be greater than or equal to 0"))
+		}
+	}
+	allErrs = append(allErrs, validateSpecStatus(ot, old)...)
+	return append(allErrs, validateApprovals(ctx, ot, old)...)
+}
+
+// validateSpec checks the steps and what they use, the params of a template may leave out their default.
+func (v *OrderStepValidator) validateSpec(ctx context.Context, ot *v1alpha1.OrderStep, requireDefaults bool) field.ErrorList {
+	allErrs := v.validateSteps(ctx, ot, field.NewPath("spec", "steps"))
+	allErrs = append(allErrs, validateParams(ot.Spec.Params, requireDefaults, field.NewPath("spec", "params"))...)
+	allErrs = append(allErrs, validateParamReferences(ot.Spec.Params, ot.Spec.Steps, field.NewPath("spec", "steps"))...)
+	allErrs = append(allErrs, validateParamReferences(ot.Spec.Params, ot.Spec.Finally, field.NewPath("spec", "finally"))...)
+	allErrs = append(allErrs, validateResults(&ot.Spec)...)
+	allErrs = append(allErrs, validateWorkspaces(ot)...)
+	allErrs = append(allErrs, validatePodTemplate(ot.Spec.PodTemplate, field.NewPath("spec", "podTemplate"))...)
+	allErrs = append(allErrs, validateRunAfter(ot)...)
+	allErrs = append(allErrs, validateParallelGroups(ot)...)
+	// activeDeadlineSeconds of the pod counts whole seconds
+	if d := ot.Spec.ActiveDeadline; d != nil && d.Duration < time.Second {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "activeDeadline"), d.Duration.String(), "must be at least 1s"))
+	}
+	return allErrs
+}
+
+// validateSpecUpdate checks only spec.status and spec.approvals change once the OrderStep is created,
+// the order of a running OrderStep refers to its steps by index. A definition never runs itself, its
+// runs execute the snapshot taken when they started, so all of its spec but the runPolicy can change.
+func validateSpecUpdate(ot, old *v1alpha1.OrderStep) field.ErrorList {
+	if old.Spec.RunPolicy == v1alpha1.RunPolicyManual {
+		if ot.Spec.RunPolicy != old.Spec.RunPolicy {
+			return field.ErrorList{field.Forbidden(field.NewPath("spec", "runPolicy"), "can not be changed once created")}
+		}
+		return nil
+	}
+	spec, oldSpec := ot.Spec.DeepCopy(), old.Spec.DeepCopy()
+	spec.Status, spec.Approvals = "", nil
+	oldSpec.Status, oldSpec.Approvals = "", nil
+	if equality.Semantic.DeepEqual(spec, oldSpec) {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("spec"), "only status and approvals can be changed once created")}
+}
+
+// validateSpecStatus checks the requested status, a cancelled OrderStep stays cancelled.
+func validateSpecStatus(ot, old *v1alpha1.OrderStep) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec", "status")
+	switch ot.Spec.Status {
+	case "", v1alpha1.OrderStepSpecStatusCancelled, v1alpha1.OrderStepSpecStatusPaused:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath, ot.Spec.Status,
+			[]string{string(v1alpha1.OrderStepSpecStatusCancelled), string(v1alpha1.OrderStepSpecStatusPaused)}))
+	}
+	if old != nil && old.Spec.Status == v1alpha1.OrderStepSpecStatusCancelled && ot.Spec.Status != old.Spec.Status {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "a cancelled OrderStep can not be resumed"))
+	}
+	return allErrs
+}
+
+// validateName checks the names generated from the base name of the objects created for name are
+// accepted by the api server.
+func validateName(baseName, name string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Subdomain(baseName) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, "pod name "+msg))
+	}
+	for _, msg := range validation.IsDNS1123Label(pod_manager.InitContainerName(baseName)) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, "init container name "+msg))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(pod_manager.ScriptsConfigMapName(baseName)) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, "scripts ConfigMap name "+msg))
+	}
+	return allErrs
+}
+
+func (v *OrderStepValidator) validateSteps(ctx context.Context, ot *v1alpha1.OrderStep, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(ot.Spec.Steps) == 0 {
+		return append(allErrs, field.Required(fldPath, "at least one step is required"))
+	}
+
+	// the finally steps are containers of the same pod, their names must not clash either
+	containerNames := map[string]struct{}{
+		pod_manager.InitContainerName(pod_manager.GenerateBaseName(ot.GetName())): {},
+	}
+	allErrs = append(allErrs, v.validateStepList(ctx, ot.Spec.Steps, 0, containerNames, fldPath)...)
+	allErrs = append(allErrs, v.validateStepList(ctx, ot.Spec.Finally, len(ot.Spec.Steps), containerNames, field.NewPath("spec", "finally"))...)
+	return allErrs
+}
+
+// validateStepList validates steps whose containers start at offset in the pod.
+func (v *OrderStepValidator) validateStepList(ctx context.Context, steps []v1alpha1.Step, offset int, containerNames map[string]struct{}, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, step := range steps {
+		idxPath := fldPath.Index(i)
+
+		containerName := pod_manager.StepContainerName(offset+i, step)
+		for _, msg := range validation.IsDNS1123Label(containerName) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), step.Name, msg))
+		}
+		if _, ok := containerNames[containerName]; ok {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), step.Name))
+		}
+		containerNames[containerName] = struct{}{}
+
+		if step.Timeout != nil && step.Timeout.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("timeout"), step.Timeout.Duration.String(), "must be greater than 0"))
+		}
+
+		if step.Retries < 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("retries"), step.Retries, "must be greater than or equal to 0"))
+		} else if step.Retries >= termination.MaxAttempts {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("retries"), step.Retries,
+				fmt.Sprintf("must be less than %d", termination.MaxAttempts)))
+		}
+		if step.Backoff != nil && step.Backoff.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("backoff"), step.Backoff.Duration.String(), "must be greater than 0"))
+		}
+
+		switch step.OnError {
+		case "", v1alpha1.OnErrorStop, v1alpha1.OnErrorContinue:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("onError"), step.OnError,
+				[]string{string(v1alpha1.OnErrorStop), string(v1alpha1.OnErrorContinue)}))
+		}
+
+		allErrs = append(allErrs, validateWhen(step.When, idxPath.Child("when"))...)
+
+		if step.Approval != nil {
+			allErrs = append(allErrs, validateApprovalStep(step, offset != 0, idxPath)...)
+			continue
+		}
+		if len(step.Image) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("image"), ""))
+			continue
+		}
+		if len(step.Script) != 0 && len(step.Command) != 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("script"), "<script>", "can not be set together with command"))
+		}
+		if len(step.Command) == 0 && len(step.Script) == 0 && len(substitution_util.References(step.Image)) == 0 {
+			allErrs = append(allErrs, v.validateImageCommand(ctx, step, idxPath)...)
+		}
+	}
+	return allErrs
+}
+
+func validateWhen(when []v1alpha1.WhenExpression, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, expr := range when {
+		idxPath := fldPath.Index(i)
+		if len(expr.Input) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("input"), ""))
+		}
+		switch expr.Operator {
+		case v1alpha1.WhenOperatorIn, v1alpha1.WhenOperatorNotIn:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("operator"), expr.Operator,
+				[]string{string(v1alpha1.WhenOperatorIn), string(v1alpha1.WhenOperatorNotIn)}))
+		}
+		if len(expr.Values) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("values"), "at least one value is required"))
+		}
+	}
+	return allErrs
+}
+
+// validateImageCommand resolves the entrypoint of the image the same way the PodManager does
+// when a step leaves its command empty, the registry has to answer before the admission request times out.
+func (v *OrderStepValidator) validateImageCommand(ctx context.Context, step v1alpha1.Step, idxPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	ctx, cancel := context.WithTimeout(ctx, imageLookupTimeout)
+	defer cancel()
+	imageInfo, err := image2.GetImageInfo(ctx, v.imageCache, step.Image)
+	if err != nil {
+		return append(allErrs, field.Invalid(idxPath.Child("image"), step.Image,
+			fmt.Sprintf("command is empty and the image could not be resolved: %s", err)))
+	}
+	if imageCmd, ok := imageInfo.Command[image2.OSArch]; !ok || len(imageCmd.Resolve(step.Args)) == 0 {
+		allErrs = append(allErrs, field.Required(idxPath.Child("command"),
+			fmt.Sprintf("image %s has neither an entrypoint nor a cmd for %s", step.Image, image2.OSArch)))
+	}
+	return allErrs
+}
+
+// validateExecutionMode checks the fields that depend on the steps running in their own pods.
+func validateExecutionMode(ot *v1alpha1.OrderStep) field.ErrorList {
+	allErrs := field.ErrorList{}
+	podPerStep := false
+	switch ot.Spec.ExecutionMode {
+	case "", v1alpha1.ExecutionModePod, v1alpha1.ExecutionModeJob:
+	case v1alpha1.ExecutionModePodPerStep:
+		podPerStep = true
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("spec", "executionMode"), ot.Spec.ExecutionMode,
+			[]string{string(v1alpha1.ExecutionModePod), string(v1alpha1.ExecutionModePodPerStep), string(v1alpha1.ExecutionModeJob)}))
+	}
+
+	if job := ot.Spec.Job; job != nil {
+		fldPath := field.NewPath("spec", "job")
+		if ot.Spec.ExecutionMode != v1alpha1.ExecutionModeJob {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "only allowed in job executionMode"))
+		}
+		if job.BackoffLimit != nil && *job.BackoffLimit < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("backoffLimit"), *job.BackoffLimit, "must be greater than or equal to 0"))
+		}
+		if job.TTLSecondsAfterFinished != nil && *job.TTLSecondsAfterFinished < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("ttlSecondsAfterFinished"), *job.TTLSecondsAfterFinished, "must be greater than or equal to 0"))
+		}
+	}
+
+	lists := []struct {
+		steps   []v1alpha1.Step
+		offset  int
+		fldPath *field.Path
+	}{
+		{ot.Spec.Steps, 0, field.NewPath("spec", "steps")},
+		{ot.Spec.Finally, len(ot.Spec.Steps), field.NewPath("spec", "finally")},
+	}
+	for _, list := range lists {
+		for i, step := range list.steps {
+			idxPath := list.fldPath.Index(i)
+			if step.PodTemplate != nil {
+				if !podPerStep {
+					allErrs = append(allErrs, field.Forbidden(idxPath.Child("podTemplate"), "only allowed in podPerStep executionMode"))
+				}
+				allErrs = append(allErrs, validatePodTemplate(step.PodTemplate, idxPath.Child("podTemplate"))...)
+			}
+			if !podPerStep {
+				continue
+			}
+			for _, msg := range validation.IsDNS1123Subdomain(pod_manager.StepPodName(pod_manager.GenerateBaseName(ot.GetName()), list.offset+i, step)) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), step.Name, "pod name "+msg))
+			}
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,340 @@
+package order_task
+
+import (
+	"context"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/daicheng123/ordertask-operator/pkg/termination"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// commandStep returns a step running a command, its image is not looked up then.
+func commandStep(name string) v1alpha1.Step {
+	return v1alpha1.Step{Container: corev1.Container{Name: name, Image: "alpine:3.18", Command: []string{"true"}}}
+}
+
+func newOrderStep(steps ...v1alpha1.Step) *v1alpha1.OrderStep {
+	return &v1alpha1.OrderStep{
+		ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "default"},
+		Spec:       v1alpha1.OrderStepSpec{Steps: steps},
+	}
+}
+
+// expectFields fails the test unless the errors are reported on the given fields, in order.
+func expectFields(t *testing.T, errs field.ErrorList, want ...string) {
+	t.Helper()
+	got := make([]string, 0, len(errs))
+	for _, err := range errs {
+		got = append(got, err.Field)
+	}
+	if want == nil {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected errors on %v, got %v", want, errs)
+	}
+}
+
+func TestValidateOrderStep(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(ot *v1alpha1.OrderStep)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(ot *v1alpha1.OrderStep) {},
+		},
+		{
+			name:   "no steps",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps = nil },
+			want:   []string{"spec.steps"},
+		},
+		{
+			name:   "missing image",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Image = "" },
+			want:   []string{"spec.steps[0].image"},
+		},
+		{
+			name:   "duplicate name",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[1].Name = "compile" },
+			want:   []string{"spec.steps[1].name"},
+		},
+		{
+			name:   "invalid container name",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Name = "compile!" },
+			want:   []string{"spec.steps[0].name"},
+		},
+		{
+			name: "step named like the init container",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Name = pod_manager.InitContainerName(pod_manager.GenerateBaseName(ot.Name))
+			},
+			want: []string{"spec.steps[0].name"},
+		},
+		{
+			name:   "negative timeout",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Timeout = &metav1.Duration{Duration: -time.Second} },
+			want:   []string{"spec.steps[0].timeout"},
+		},
+		{
+			name:   "activeDeadline",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.ActiveDeadline = &metav1.Duration{Duration: time.Minute} },
+		},
+		{
+			name: "activeDeadline below a second",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.ActiveDeadline = &metav1.Duration{Duration: 500 * time.Millisecond}
+			},
+			want: []string{"spec.activeDeadline"},
+		},
+		{
+			name:   "retries",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Retries = termination.MaxAttempts - 1 },
+		},
+		{
+			name:   "negative retries",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Retries = -1 },
+			want:   []string{"spec.steps[0].retries"},
+		},
+		{
+			name:   "too many retries",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Retries = termination.MaxAttempts },
+			want:   []string{"spec.steps[0].retries"},
+		},
+		{
+			name:   "zero backoff",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Backoff = &metav1.Duration{} },
+			want:   []string{"spec.steps[0].backoff"},
+		},
+		{
+			name:   "onError continue",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].OnError = v1alpha1.OnErrorContinue },
+		},
+		{
+			name:   "unknown onError",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].OnError = "ignore" },
+			want:   []string{"spec.steps[0].onError"},
+		},
+		{
+			name:   "finally",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Finally = []v1alpha1.Step{commandStep("cleanup")} },
+		},
+		{
+			name:   "finally named like a step",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Finally = []v1alpha1.Step{commandStep("test")} },
+			want:   []string{"spec.finally[0].name"},
+		},
+		{
+			name: "finally without image",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Finally = []v1alpha1.Step{commandStep("cleanup")}
+				ot.Spec.Finally[0].Image = ""
+			},
+			want: []string{"spec.finally[0].image"},
+		},
+		{
+			name: "when",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[1].When = []v1alpha1.WhenExpression{
+					{Input: "$(steps.compile.reason)", Operator: v1alpha1.WhenOperatorNotIn, Values: []string{v1alpha1.StepReasonFailed}},
+				}
+			},
+		},
+		{
+			name: "incomplete when",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[1].When = []v1alpha1.WhenExpression{{Operator: "equals"}}
+			},
+			want: []string{"spec.steps[1].when[0].input", "spec.steps[1].when[0].operator", "spec.steps[1].when[0].values"},
+		},
+		{
+			// the image is not looked up for a script
+			name: "script",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Command = nil
+				ot.Spec.Steps[0].Script = "go build ./..."
+			},
+		},
+		{
+			name:   "script and command",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Script = "go build ./..." },
+			want:   []string{"spec.steps[0].script"},
+		},
+		{
+			name:   "unknown executionMode",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.ExecutionMode = "deployment" },
+			want:   []string{"spec.executionMode"},
+		},
+		{
+			name: "podTemplate of a step pod",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.ExecutionMode = v1alpha1.ExecutionModePodPerStep
+				ot.Spec.Steps[1].PodTemplate = &v1alpha1.PodTemplate{ServiceAccountName: "tester"}
+			},
+		},
+		{
+			name: "podTemplate of a step in a single pod",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[1].PodTemplate = &v1alpha1.PodTemplate{ServiceAccountName: "tester"}
+			},
+			want: []string{"spec.steps[1].podTemplate"},
+		},
+		{
+			name: "step pod name too long",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.ExecutionMode = v1alpha1.ExecutionModePodPerStep
+				ot.Spec.Steps[1].Name = strings.Repeat("a", 63)
+				ot.Name = strings.Repeat("b", 200)
+			},
+			want: []string{"spec.steps[1].name"},
+		},
+		{
+			name: "job",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.ExecutionMode = v1alpha1.ExecutionModeJob
+				ot.Spec.Job = &v1alpha1.JobOptions{BackoffLimit: pointer.Int32(2), TTLSecondsAfterFinished: pointer.Int32(0)}
+			},
+		},
+		{
+			name:   "job options of a pod",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Job = &v1alpha1.JobOptions{BackoffLimit: pointer.Int32(2)} },
+			want:   []string{"spec.job"},
+		},
+		{
+			name: "negative job options",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.ExecutionMode = v1alpha1.ExecutionModeJob
+				ot.Spec.Job = &v1alpha1.JobOptions{BackoffLimit: pointer.Int32(-1), TTLSecondsAfterFinished: pointer.Int32(-1)}
+			},
+			want: []string{"spec.job.backoffLimit", "spec.job.ttlSecondsAfterFinished"},
+		},
+		{
+			name:   "created paused",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Status = v1alpha1.OrderStepSpecStatusPaused },
+		},
+		{
+			name:   "unknown status",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Status = "Stopped" },
+			want:   []string{"spec.status"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOrderStep(commandStep("compile"), commandStep("test"))
+			tt.modify(ot)
+			v := NewOrderStepValidator(nil, nil)
+			expectFields(t, v.validateOrderStep(context.Background(), ot, nil), tt.want...)
+		})
+	}
+}
+
+func TestValidateOrderStepUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    v1alpha1.OrderStepSpecStatus
+		runPolicy v1alpha1.RunPolicy
+		modify    func(ot *v1alpha1.OrderStep)
+		want      []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(ot *v1alpha1.OrderStep) {},
+		},
+		{
+			name:   "status changed",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Status = v1alpha1.OrderStepSpecStatusPaused },
+		},
+		{
+			name:   "step removed",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps = ot.Spec.Steps[:1] },
+			want:   []string{"spec"},
+		},
+		{
+			name:   "command changed",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[0].Command = []string{"false"} },
+			want:   []string{"spec"},
+		},
+		{
+			name:   "cancelled",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Status = v1alpha1.OrderStepSpecStatusCancelled },
+		},
+		{
+			name:   "cancelled and resumed",
+			status: v1alpha1.OrderStepSpecStatusCancelled,
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Status = "" },
+			want:   []string{"spec.status"},
+		},
+		{
+			name:   "paused and resumed",
+			status: v1alpha1.OrderStepSpecStatusPaused,
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Status = "" },
+		},
+		{
+			name:      "definition edited",
+			runPolicy: v1alpha1.RunPolicyManual,
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps = append(ot.Spec.Steps[:1], commandStep("lint"))
+				ot.Spec.Steps[0].Command = []string{"make"}
+			},
+		},
+		{
+			name:      "definition edited into an invalid one",
+			runPolicy: v1alpha1.RunPolicyManual,
+			modify:    func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[1].Name = "compile" },
+			want:      []string{"spec.steps[1].name"},
+		},
+		{
+			name:      "definition made to run",
+			runPolicy: v1alpha1.RunPolicyManual,
+			modify:    func(ot *v1alpha1.OrderStep) { ot.Spec.RunPolicy = v1alpha1.RunPolicyAuto },
+			want:      []string{"spec.runPolicy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := newOrderStep(commandStep("compile"), commandStep("test"))
+			old.Spec.Status = tt.status
+			old.Spec.RunPolicy = tt.runPolicy
+			ot := old.DeepCopy()
+			tt.modify(ot)
+			v := NewOrderStepValidator(nil, nil)
+			expectFields(t, v.validateOrderStep(context.Background(), ot, old), tt.want...)
+		})
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name     string
+		taskName string
+		want     []string
+	}{
+		{name: "short", taskName: "build"},
+		{name: "underscores", taskName: "build_all"},
+		{
+			// the init container is a DNS label of at most 63 characters
+			name:     "too long for the init container",
+			taskName: strings.Repeat("a", 50),
+			want:     []string{"metadata.name"},
+		},
+		{
+			name:     "too long for the pod",
+			taskName: strings.Repeat("a", 250),
+			want:     []string{"metadata.name", "metadata.name", "metadata.name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateName(pod_manager.GenerateBaseName(tt.taskName), tt.taskName, field.NewPath("metadata", "name"))
+			expectFields(t, errs, tt.want...)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/lru"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"time"
+)
+
+const (
+	defaultImageSize = 100
+	// imageLookupTimeout bounds a registry lookup, the api server gives up on the webhook after 10s
+	imageLookupTimeout = 5 * time.Second
+)
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks of OrderStep, OrderStepRun,
+// CronOrderStep and the templates, they share one image cache so an image is only resolved once per admission.
+func SetupWebhookWithManager(mgr ctrl.Manager) error {
+	imageCache := lru.New(defaultImageSize)
+	defaulter := NewOrderStepDefaulter(imageCache)
+	validator := NewOrderStepValidator(imageCache, mgr.GetAPIReader())
+	err := ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.OrderStep{}).
+		WithDefaulter(defaulter).
+		WithValidator(validator).
+		Complete()
+	if err != nil {
+		return err
+	}
+	err = ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.OrderStepRun{}).
+		WithDefaulter(NewRunDefaulter()).
+		WithValidator(NewRunValidator(validator)).
+		Complete()
+	if err != nil {
+		return err
+	}
+	err = ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.CronOrderStep{}).
+		WithValidator(NewCronValidator(validator)).
+		Complete()
+	if err != nil {
+		return err
+	}
+	for _, template := range []runtime.Object{&v1alpha1.OrderStepTemplate{}, &v1alpha1.ClusterOrderStepTemplate{}} {
+		err = ctrl.NewWebhookManagedBy(mgr).
+			For(template).
+			WithDefaulter(NewTemplateDefaulter(defaulter)).
+			WithValidator(NewTemplateValidator(validator)).
+			Complete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package order_task
+
+import (
+	"fmt"
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	"github.com/daicheng123/ordertask-operator/manager/pod_manager"
+	"github.com/daicheng123/ordertask-operator/pkg/utils/substitution_util"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"path"
+	"strings"
+)
+
+// reservedMountPaths are mounted into every step by the PodManager.
+var reservedMountPaths = []string{"/entrypoint/bin", "/etc/podinfo", "/ordertask"}
+
+// validateWorkspaces checks the declared workspaces and the ones mounted by the steps.
+func validateWorkspaces(ot *v1alpha1.OrderStep) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec", "workspaces")
+	declared := make(map[string]v1alpha1.WorkspaceDeclaration, len(ot.Spec.Workspaces))
+	for i, ws := range ot.Spec.Workspaces {
+		idxPath := fldPath.Index(i)
+		for _, msg := range validation.IsDNS1123Label(pod_manager.WorkspaceVolumeName(ws.Name)) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), ws.Name, msg))
+		}
+		if _, ok := declared[ws.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), ws.Name))
+		}
+		declared[ws.Name] = ws
+
+		sources := 0
+		for _, set := range []bool{ws.PersistentVolumeClaim != nil, ws.VolumeClaimTemplate != nil,
+			ws.ConfigMap != nil, ws.Secret != nil, ws.EmptyDir != nil} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			allErrs = append(allErrs, field.Invalid(idxPath, ws.Name,
+				"exactly one of persistentVolumeClaim, volumeClaimTemplate, configMap, secret or emptyDir must be set"))
+		}
+		if ws.VolumeClaimTemplate != nil {
+			claimName := pod_manager.WorkspaceClaimName(pod_manager.GenerateBaseName(ot.GetName()), ws.Name)
+			for _, msg := range validation.IsDNS1123Subdomain(claimName) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), ws.Name, "claim name "+msg))
+			}
+		}
+		if len(ws.MountPath) != 0 {
+			allErrs = append(allErrs, validateMountPath(ws.MountPath, idxPath.Child("mountPath"))...)
+		}
+	}
+
+	allErrs = append(allErrs, validateStepWorkspaces(ot.Spec.Steps, declared, field.NewPath("spec", "steps"))...)
+	allErrs = append(allErrs, validateStepWorkspaces(ot.Spec.Finally, declared, field.NewPath("spec", "finally"))...)
+	return allErrs
+}
+
+func validateStepWorkspaces(steps []v1alpha1.Step, declared map[string]v1alpha1.WorkspaceDeclaration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, step := range steps {
+		idxPath := fldPath.Index(i)
+		mounted := make(map[string]struct{}, len(step.Workspaces))
+		mountPaths := make(map[string]struct{}, len(step.Workspaces))
+		for j, usage := range step.Workspaces {
+			usagePath := idxPath.Child("workspaces").Index(j)
+			ws, ok := declared[usage.Name]
+			if !ok {
+				allErrs = append(allErrs, field.NotFound(usagePath.Child("name"), usage.Name))
+				continue
+			}
+			if _, ok := mounted[usage.Name]; ok {
+				allErrs = append(allErrs, field.Duplicate(usagePath.Child("name"), usage.Name))
+			}
+			mounted[usage.Name] = struct{}{}
+
+			if len(usage.MountPath) != 0 {
+				allErrs = append(allErrs, validateMountPath(usage.MountPath, usagePath.Child("mountPath"))...)
+			}
+			mountPath := path.Clean(pod_manager.WorkspaceMountPath(ws, usage))
+			if _, ok := mountPaths[mountPath]; ok {
+				allErrs = append(allErrs, field.Duplicate(usagePath.Child("mountPath"), mountPath))
+			}
+			mountPaths[mountPath] = struct{}{}
+		}
+
+		for _, value := range stepStrings(step) {
+			for _, name := range substitution_util.References(value.value) {
+				if !strings.HasPrefix(name, "workspaces.") {
+					continue
+				}
+				workspace := strings.TrimSuffix(strings.TrimPrefix(name, "workspaces."), ".path")
+				if _, ok := mounted[workspace]; !ok || !strings.HasSuffix(name, ".path") {
+					allErrs = append(allErrs, field.Invalid(idxPath.Child(value.field), value.value,
+						fmt.Sprintf("%s is not the path of a workspace mounted by the step", name)))
+				}
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateMountPath(mountPath string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !path.IsAbs(mountPath) {
+		return append(allErrs, field.Invalid(fldPath, mountPath, "must be an absolute path"))
+	}
+	cleaned := path.Clean(mountPath)
+	for _, reserved := range reservedMountPaths {
+		if cleaned == reserved || strings.HasPrefix(cleaned, reserved+"/") {
+			allErrs = append(allErrs, field.Invalid(fldPath, mountPath, fmt.Sprintf("%s is reserved", reserved)))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,99 @@
+package order_task
+
+import (
+	"github.com/daicheng123/ordertask-operator/api/tasks/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestValidateWorkspaces(t *testing.T) {
+	source := corev1.PersistentVolumeClaimVolumeSource{ClaimName: "sources"}
+	tests := []struct {
+		name   string
+		modify func(ot *v1alpha1.OrderStep)
+		want   []string
+	}{
+		{
+			name: "mounted and used",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Workspaces = []v1alpha1.WorkspaceUsage{{Name: "src"}, {Name: "cache", MountPath: "/root/.cache"}}
+				ot.Spec.Steps[0].Args = []string{"-C", "$(workspaces.src.path)"}
+			},
+		},
+		{
+			name:   "invalid name",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Workspaces[0].Name = "Src" },
+			want:   []string{"spec.workspaces[0].name"},
+		},
+		{
+			name:   "duplicate name",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Workspaces[1].Name = "src" },
+			want:   []string{"spec.workspaces[1].name"},
+		},
+		{
+			name:   "no source",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Workspaces[1].EmptyDir = nil },
+			want:   []string{"spec.workspaces[1]"},
+		},
+		{
+			name:   "two sources",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Workspaces[0].EmptyDir = &corev1.EmptyDirVolumeSource{} },
+			want:   []string{"spec.workspaces[0]"},
+		},
+		{
+			name:   "relative mountPath",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Workspaces[0].MountPath = "src" },
+			want:   []string{"spec.workspaces[0].mountPath"},
+		},
+		{
+			name:   "reserved mountPath",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Workspaces[0].MountPath = "/ordertask/src" },
+			want:   []string{"spec.workspaces[0].mountPath"},
+		},
+		{
+			name: "not declared",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Workspaces = []v1alpha1.WorkspaceUsage{{Name: "output"}}
+			},
+			want: []string{"spec.steps[0].workspaces[0].name"},
+		},
+		{
+			name: "mounted twice",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Workspaces = []v1alpha1.WorkspaceUsage{{Name: "src"}, {Name: "src", MountPath: "/src"}}
+			},
+			want: []string{"spec.steps[0].workspaces[1].name"},
+		},
+		{
+			name: "same mountPath",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Steps[0].Workspaces = []v1alpha1.WorkspaceUsage{{Name: "src"}, {Name: "cache", MountPath: "/workspace/src/"}}
+			},
+			want: []string{"spec.steps[0].workspaces[1].mountPath"},
+		},
+		{
+			name:   "path of a workspace not mounted",
+			modify: func(ot *v1alpha1.OrderStep) { ot.Spec.Steps[1].Args = []string{"$(workspaces.src.path)"} },
+			want:   []string{"spec.steps[1].args"},
+		},
+		{
+			name: "finally step",
+			modify: func(ot *v1alpha1.OrderStep) {
+				ot.Spec.Finally = []v1alpha1.Step{commandStep("cleanup")}
+				ot.Spec.Finally[0].Workspaces = []v1alpha1.WorkspaceUsage{{Name: "output"}}
+			},
+			want: []string{"spec.finally[0].workspaces[0].name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOrderStep(commandStep("compile"), commandStep("test"))
+			ot.Spec.Workspaces = []v1alpha1.WorkspaceDeclaration{
+				{Name: "src", PersistentVolumeClaim: source.DeepCopy()},
+				{Name: "cache", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}
+			tt.modify(ot)
+			expectFields(t, validateWorkspaces(ot), tt.want...)
+		})
+	}
+}